@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	runes "github.com/bolt-observer/go-runes/runes"
+	"github.com/bolt-observer/go-runes/runes/lint"
+)
+
+const (
+	// exitOK is returned when no errors were found
+	exitOK = 0
+	// exitFindings is returned when at least one finding is an error
+	exitFindings = 1
+	// exitUsage is returned for invalid flags or input
+	exitUsage = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout))
+}
+
+// run lints restrictions given as arguments (or one per line on stdin), prints findings to stdout and returns
+// the exit code
+func run(args []string, stdin io.Reader, stdout io.Writer) int {
+	flags := flag.NewFlagSet("runelint", flag.ContinueOnError)
+	flags.SetOutput(stdout)
+	isRune := flags.Bool("rune", false, "inputs are encoded runes instead of restriction strings")
+	disable := flags.String("disable", "", "comma separated list of checks to disable")
+	timeField := flags.String("time", "time", "field used by the time-upper-bound check")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	disabled := make(map[string]bool)
+	for _, name := range strings.Split(*disable, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			disabled[name] = true
+		}
+	}
+
+	checks := make([]lint.Check, 0)
+	for _, check := range lint.DefaultChecks() {
+		if check.Name == "time-upper-bound" {
+			check = lint.TimeUpperBound(*timeField)
+		}
+		if disabled[check.Name] {
+			delete(disabled, check.Name)
+			continue
+		}
+		checks = append(checks, check)
+	}
+	for name := range disabled {
+		fmt.Fprintf(stdout, "unknown check %s\n", name)
+		return exitUsage
+	}
+
+	inputs := flags.Args()
+	if len(inputs) == 0 {
+		scanner := bufio.NewScanner(stdin)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				inputs = append(inputs, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Fprintf(stdout, "reading input: %v\n", err)
+			return exitUsage
+		}
+	}
+
+	ret := exitOK
+	for _, input := range inputs {
+		var (
+			restrictions []runes.Restriction
+			err          error
+		)
+		if *isRune {
+			var r *runes.Rune
+			r, _, err = runes.Parse(input)
+			if r != nil {
+				restrictions = r.Restrictions
+			}
+		} else {
+			restrictions, err = runes.MakeRestrictionsFromString(input)
+		}
+		if err != nil {
+			fmt.Fprintf(stdout, "%s: %v\n", input, err)
+			return exitUsage
+		}
+
+		findings := lint.Lint(restrictions, checks)
+		for _, finding := range findings {
+			fmt.Fprintf(stdout, "%s: %s\n", input, finding)
+		}
+		if lint.HasErrors(findings) {
+			ret = exitFindings
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	runes "github.com/bolt-observer/go-runes/runes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunExitCode(t *testing.T) {
+	master := runes.MustMakeMasterRune([]byte("secret"))
+	r := master.MustGetRestrictedFromString("time<abc")
+
+	for _, c := range []struct {
+		args   []string
+		stdin  string
+		code   int
+		output string
+	}{
+		{[]string{"method=pay&time<100"}, "", exitOK, ""},
+		{[]string{"method=pay"}, "", exitOK, "method=pay: restrictions: warning: no upper bound on time, rune never expires (time-upper-bound)\n"},
+		{[]string{"-disable", "time-upper-bound", "method=pay"}, "", exitOK, ""},
+		{[]string{"-time", "expiry", "expiry<100"}, "", exitOK, ""},
+		{nil, "expiry<100\n\ntime<abc\n", exitFindings, "expiry<100: restrictions: warning: no upper bound on time, rune never expires (time-upper-bound)\n" +
+			"time<abc: restrictions: warning: no upper bound on time, rune never expires (time-upper-bound)\n" +
+			"time<abc: restriction 0 alternative 0: error: time<abc compares against non-numeric \"abc\" and never passes (non-numeric-comparison)\n"},
+		{[]string{"-rune", "-disable", "time-upper-bound", r.ToBase64()}, "", exitFindings, ""},
+		{[]string{"-disable", "nosuchcheck", "method=pay"}, "", exitUsage, "unknown check nosuchcheck\n"},
+		{[]string{"method"}, "", exitUsage, ""},
+		{[]string{"-nosuchflag"}, "", exitUsage, ""},
+	} {
+		var out bytes.Buffer
+		code := run(c.args, strings.NewReader(c.stdin), &out)
+		assert.Equal(t, c.code, code, c.args)
+		if c.output != "" {
+			assert.Equal(t, c.output, out.String(), c.args)
+		}
+	}
+}
@@ -1,12 +1,16 @@
 package runes
 
 import (
+	"context"
 	"fmt"
-	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
+// asciiPunct is the same as Python's string.punctuation
+const asciiPunct = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
 // KnownConditions are the currently known conditions
 var KnownConditions = []string{"!", "=", "/", "^", "$", "~", "<", ">", "}", "{", "#"}
 
@@ -20,9 +24,9 @@ type Alternative struct {
 // ObtainValue is the signature of a function to get current value
 type ObtainValue func() any
 
-func containsPunctuation(s string) bool {
+func containsPunctuation(s string, opts ParseOptions) bool {
 	for _, c := range s {
-		if isPunct(c) {
+		if opts.isPunct(c) {
 			return true
 		}
 	}
@@ -42,80 +46,104 @@ func knownCondition(cond string) bool {
 
 // MakeAlternative returns a new Alternative
 func MakeAlternative(field string, cond string, value any, allowIDField bool) (*Alternative, error) {
-	if containsPunctuation(field) {
-		return nil, fmt.Errorf("field not valid")
+	return makeAlternative(field, cond, value, allowIDField, ParseOptions{})
+}
+
+func makeAlternative(field string, cond string, value any, allowIDField bool, opts ParseOptions) (*Alternative, error) {
+	if containsPunctuation(field, opts) {
+		return nil, newParseError(ParseBadField, 0, field, "field not valid")
 	}
 
 	if field == "" {
 		if !allowIDField {
-			return nil, fmt.Errorf("uniqueId field not valid here")
+			return nil, newParseError(ParseMisplacedUniqueID, 0, cond, "uniqueId field not valid here")
 		}
 		if cond != "=" {
-			return nil, fmt.Errorf("uniqueId condition must be '='")
+			return nil, newParseError(ParseMisplacedUniqueID, 0, cond, "uniqueId condition must be '='")
 		}
 	}
 
 	if !knownCondition(cond) {
-		return nil, fmt.Errorf("cond not valid %s", cond)
+		return nil, newParseError(ParseUnknownOperator, len(field), cond, fmt.Sprintf("cond not valid %s", cond))
 	}
 
 	return &Alternative{Field: field, Cond: cond, Value: value}, nil
 }
 
-// MakeAlternativeFromString returns a new alternativee from a string
+// MakeAlternativeFromString returns a new alternativee from a string.
+//
+// Parsing works on byte offsets. Field is everything before the first punctuation character (which is the operator),
+// so a field may contain any non-punctuation UTF-8 characters (letters of any script, digits, emoji, combining marks...).
+// Value is taken verbatim as bytes up to the first unescaped '|' or '&', no Unicode normalization is performed
+// (e.g. "e\u0301" and "\u00e9" are different values).
 func MakeAlternativeFromString(str string, allowIDField bool) (*Alternative, string, error) {
+	return parseAlternative(str, allowIDField, ParseOptions{})
+}
 
-	offset := 0
-
+func parseAlternative(str string, allowIDField bool, opts ParseOptions) (*Alternative, string, error) {
+	offset := -1
 	cond := ""
-	for _, r := range str {
-		if isPunct(r) {
+	for i, r := range str {
+		if opts.isPunct(r) {
 			cond = string(r)
+			offset = i
 			break
 		}
-		offset++
 	}
 
 	if cond == "" {
-		return nil, "", fmt.Errorf("%s does not contain any operator", str)
+		return nil, "", newParseError(ParseUnknownOperator, len(str), str, "does not contain any operator")
 	}
 
 	field := str[0:offset]
-	offset++
-
-	var sb strings.Builder
-
-	offset2 := 0
-	for _, r := range str[offset:] {
-		if r == '|' {
-			offset2++
+	offset += len(cond)
+
+	// Backslash escapes the next byte (so '\\', '|' and '&' can appear in value)
+	value := make([]byte, 0, len(str)-offset)
+	rest := ""
+	for i := offset; i < len(str); i++ {
+		c := str[i]
+		if c == '|' {
+			rest = str[i+1:]
 			break
 		}
-		if r == '&' {
+		if c == '&' {
+			rest = str[i:]
 			break
 		}
-		if r == '\\' {
-			offset2++
-			continue
+		if c == '\\' {
+			i++
+			if i >= len(str) {
+				return nil, "", newParseError(ParseBadEscape, i-1, "\\", "value ends with unfinished escape")
+			}
+			c = str[i]
 		}
 
-		sb.WriteRune(r)
-		offset2++
+		value = append(value, c)
 	}
 
-	alt, err := MakeAlternative(field, cond, sb.String(), allowIDField)
+	alt, err := makeAlternative(field, cond, string(value), allowIDField, opts)
 	if err != nil {
 		return nil, "", err
 	}
 
-	return alt, str[offset+offset2:], nil
+	return alt, rest, nil
 }
 
+// escape escapes value the same way as the reference implementation (every '\\', '|' and '&' is prefixed with '\\')
 func escape(s string) string {
-	str := strings.ReplaceAll(s, "&", "\\&")
-	str = strings.ReplaceAll(str, "|", "\\|")
-	str = strings.ReplaceAll(str, "\\", "\\\\")
-	return str
+	var sb strings.Builder
+	sb.Grow(len(s))
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\\' || c == '|' || c == '&' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(c)
+	}
+
+	return sb.String()
 }
 
 // IsUniqueID - is this alternative the unique id
@@ -123,207 +151,140 @@ func (a *Alternative) IsUniqueID() bool {
 	return a.Field == ""
 }
 
+// IsNumeric - is the value a number (as understood by < and >)
+func (a *Alternative) IsNumeric() bool {
+	_, ok := parseNumber(fmt.Sprintf("%v", a.Value))
+	return ok
+}
+
 // String returns a string representation
 func (a *Alternative) String() string {
-	s, ok := a.Value.(string)
-	if ok {
-		return fmt.Sprintf("%s%s%s", a.Field, a.Cond, escape(s))
-	}
-
-	return fmt.Sprintf("%s%s%v", a.Field, a.Cond, a.Value)
+	return a.Field + a.Cond + escape(fmt.Sprintf("%v", a.Value))
 }
 
 // Evaluate evaluates the alternative
 func (a *Alternative) Evaluate(vals map[string]any) (bool, string) {
+	result := a.EvaluateResult(vals)
+	return result.Outcome == OutcomePass, result.Reason
+}
+
+// EvaluateResult evaluates the alternative and records the details
+func (a *Alternative) EvaluateResult(vals map[string]any) AlternativeResult {
+	return a.evaluateResult(contextLookup(context.Background(), MapValues(vals)), EvalOptions{})
+}
+
+func (a *Alternative) evaluateResult(lookup lookupFunc, opts EvalOptions) AlternativeResult {
+	result := AlternativeResult{Field: a.Field, Operator: a.Cond, Expected: fmt.Sprintf("%v", a.Value), Outcome: OutcomePass}
+	fail := func(reason string) AlternativeResult {
+		result.Outcome = OutcomeFail
+		result.Reason = reason
+		return result
+	}
+	failErr := func(err error) AlternativeResult {
+		result.Outcome = OutcomeError
+		result.Reason = err.Error()
+		result.err = err
+		return result
+	}
+
 	if a.Cond == "#" {
-		return true, ""
+		return result
 	}
 
-	if _, ok := vals[a.Field]; !ok {
+	actualValue, ok, err := lookup(a.Field)
+	if err != nil {
+		return failErr(err)
+	}
+	if !ok {
 		if a.IsUniqueID() {
 			s, ok := a.Value.(string)
 			if !ok {
-				return false, "unique id should be string"
+				return fail("unique id should be string")
 			}
 
 			if strings.Contains(s, "-") {
-				return false, fmt.Sprintf("unknown version %v", a.Value)
+				return fail(fmt.Sprintf("unknown version %v", a.Value))
 			}
 		}
 		if a.Cond != "!" {
-			return false, fmt.Sprintf("%s is missing", a.Field)
+			return fail(fmt.Sprintf("%s is missing", a.Field))
 		}
-		return true, ""
+		return result
 	}
 
-	actualValue := vals[a.Field]
-	obtainer, ok := actualValue.(ObtainValue)
-	if ok {
-		actualValue = obtainer()
+	actual := valueString(actualValue)
+	result.Actual = &actual
+
+	if opts.Strict {
+		_, numeric := parseNumber(result.Expected)
+		if err := typeMismatch(a.Field, a.Cond, actualValue, result.Expected, numeric); err != nil {
+			return failErr(err)
+		}
 	}
 
 	switch a.Cond {
 	case "!":
-		return false, fmt.Sprintf("%s is present", a.Field)
+		return fail(fmt.Sprintf("%s is present", a.Field))
 	case "=":
-		ret, err := isEqual(actualValue, a.Value)
-		if ret && err == nil {
-			return true, ""
+		if actual == result.Expected {
+			return result
 		}
-		return false, fmt.Sprintf("!= %s", a.Value)
+		return fail(fmt.Sprintf("!= %s", a.Value))
 	case "/":
-		ret, err := isEqual(actualValue, a.Value)
-		if !ret && err == nil {
-			return true, ""
+		if actual != result.Expected {
+			return result
 		}
-		return false, fmt.Sprintf("= %s", a.Value)
+		return fail(fmt.Sprintf("= %s", a.Value))
 	case "^":
 		// starts with
-		val := fmt.Sprintf("%v", a.Value)
-		entry := fmt.Sprintf("%v", actualValue)
-
-		if strings.HasPrefix(entry, val) {
-			return true, ""
+		if strings.HasPrefix(actual, result.Expected) {
+			return result
 		}
-		return false, fmt.Sprintf("does not start with %s", val)
+		return fail(fmt.Sprintf("does not start with %s", result.Expected))
 	case "$":
 		// ends with
-		val := fmt.Sprintf("%v", a.Value)
-		entry := fmt.Sprintf("%v", actualValue)
-
-		if strings.HasSuffix(entry, val) {
-			return true, ""
+		if strings.HasSuffix(actual, result.Expected) {
+			return result
 		}
-		return false, fmt.Sprintf("does not end with %s", val)
+		return fail(fmt.Sprintf("does not end with %s", result.Expected))
 	case "~":
 		// contains
-		val := fmt.Sprintf("%v", a.Value)
-		entry := fmt.Sprintf("%v", actualValue)
-
-		if strings.Contains(entry, val) {
-			return true, ""
+		if strings.Contains(actual, result.Expected) {
+			return result
 		}
-		return false, fmt.Sprintf("does not contain %s", val)
+		return fail(fmt.Sprintf("does not contain %s", result.Expected))
 	case "<":
-		ret, err := isLower(actualValue, a.Value)
+		ret, err := isLower(actualValue, result.Expected)
 		if ret && err == nil {
-			return true, ""
+			return result
 		}
-		return false, fmt.Sprintf(">= %v", a.Value)
+		return fail(fmt.Sprintf(">= %v", a.Value))
 	case ">":
-		ret, err := isHigher(actualValue, a.Value)
+		ret, err := isHigher(actualValue, result.Expected)
 		if ret && err == nil {
-			return true, ""
+			return result
 		}
-		return false, fmt.Sprintf("<= %v", a.Value)
+		return fail(fmt.Sprintf("<= %v", a.Value))
 	case "{":
-		ret := lexoCmp(actualValue, a.Value)
-		if ret < 0 {
-			return true, ""
+		if actual < result.Expected {
+			return result
 		}
-		return false, fmt.Sprintf("is the same or ordered after %v", actualValue)
+		return fail(fmt.Sprintf("is the same or ordered after %s", result.Expected))
 	case "}":
-		ret := lexoCmp(vals[a.Field], a.Value)
-		if ret > 0 {
-			return true, ""
+		if actual > result.Expected {
+			return result
 		}
-		return false, fmt.Sprintf("is the same or ordered before %v", actualValue)
+		return fail(fmt.Sprintf("is the same or ordered before %s", result.Expected))
 	default:
-		return false, fmt.Sprintf("unhandled case: %v", a.Cond)
+		return fail(fmt.Sprintf("unhandled case: %v", a.Cond))
 	}
 }
 
-func isPunct(r rune) bool {
-	punc := `!"#$%&'()*+,-./:;<=>?@[\]^_{|}~"`
-	punc += "`"
-
-	// Because some chars like "+" are apparently not unicode punctuations
-	return unicode.IsPunct(r) || strings.ContainsRune(punc, r)
+func isASCIIPunct(r rune) bool {
+	return r < utf8.RuneSelf && strings.ContainsRune(asciiPunct, r)
 }
 
-// Wake me up when golang gets better generics, until then we do some ugly hacks with "any" (I'd rather use comparable and constraints.Ordered)
-
-func toInt(a, b string) (int64, int64, error) {
-	numA, err := strconv.ParseInt(a, 10, 64)
-	if err != nil {
-		return 0, 0, err
-	}
-	numB, err := strconv.ParseInt(b, 10, 64)
-	if err != nil {
-		return 0, 0, err
-	}
-
-	return numA, numB, nil
-}
-
-func toFloat(a, b string) (float64, float64, error) {
-	numA, err := strconv.ParseFloat(a, 64)
-	if err != nil {
-		return 0, 0, err
-	}
-	numB, err := strconv.ParseFloat(b, 64)
-	if err != nil {
-		return 0, 0, err
-	}
-
-	return numA, numB, nil
-}
-
-func isLower(a, b any) (bool, error) {
-	one := fmt.Sprintf("%v", a)
-	two := fmt.Sprintf("%v", b)
-
-	intA, intB, err := toInt(one, two)
-	if err == nil {
-		return intA < intB, nil
-	}
-
-	floatA, floatB, err := toFloat(one, two)
-	if err == nil {
-		return floatA < floatB, nil
-	}
-
-	return false, fmt.Errorf("could not compare")
-}
-
-func isHigher(a, b any) (bool, error) {
-	one := fmt.Sprintf("%v", a)
-	two := fmt.Sprintf("%v", b)
-
-	intA, intB, err := toInt(one, two)
-	if err == nil {
-		return intA > intB, nil
-	}
-
-	floatA, floatB, err := toFloat(one, two)
-	if err == nil {
-		return floatA > floatB, nil
-	}
-
-	return false, fmt.Errorf("could not compare")
-}
-
-func isEqual(a, b any) (bool, error) {
-	one := fmt.Sprintf("%v", a)
-	two := fmt.Sprintf("%v", b)
-
-	return one == two, nil
-}
-
-func lexoCmp(f, v any) int {
-	field := fmt.Sprintf("%v", f)
-	val := fmt.Sprintf("%v", v)
-
-	length := len(field)
-	if len(val) < length {
-		length = len(val)
-	}
-	cmp := strings.Compare(field, val[0:length])
-
-	/* If val is same but longer, field is < */
-	if cmp == 0 && len(val) > len(field) {
-		cmp = -1
-	}
-
-	return cmp
+func isPunct(r rune) bool {
+	// Because some chars like "+" are apparently not unicode punctuations
+	return unicode.IsPunct(r) || isASCIIPunct(r)
 }
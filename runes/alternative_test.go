@@ -16,6 +16,13 @@ func TestIsLower(t *testing.T) {
 	assert.Equal(t, true, ret)
 }
 
+func TestIsNumeric(t *testing.T) {
+	for value, expected := range map[any]bool{"1": true, -5: true, "1.5": true, "1e3": true, "18446744073709551616": true, "abc": false, "": false} {
+		alt := Alternative{Field: "time", Cond: "<", Value: value}
+		assert.Equal(t, expected, alt.IsNumeric(), value)
+	}
+}
+
 func TestIsEqual(t *testing.T) {
 	ret, err := isEqual(uint16(12), 12.0)
 	assert.NoError(t, err)
@@ -138,3 +145,43 @@ func TestObtainValue(t *testing.T) {
 	eval, _ := resp.Evaluate(vals)
 	assert.Equal(t, true, eval)
 }
+
+func TestEscape(t *testing.T) {
+	for value, expected := range map[string]string{
+		"plain":   "plain",
+		`a\b`:     `a\\b`,
+		"a&b":     `a\&b`,
+		"a|b":     `a\|b`,
+		`\&`:      `\\\&`,
+		`\\`:      `\\\\`,
+		`x|&\y\`:  `x\|\&\\y\\`,
+		"":        "",
+		"a=b^c$d": "a=b^c$d",
+	} {
+		a, err := MakeAlternative("f", "=", value, false)
+		assert.NoError(t, err)
+		assert.Equal(t, "f="+expected, a.String())
+
+		parsed, rest, err := MakeAlternativeFromString(a.String(), false)
+		assert.NoError(t, err)
+		assert.Equal(t, "", rest)
+		assert.Equal(t, value, parsed.Value)
+	}
+}
+
+func TestUnfinishedEscape(t *testing.T) {
+	_, _, err := MakeAlternativeFromString(`f=abc\`, false)
+	assert.ErrorIs(t, err, ErrInvalidRune)
+}
+
+func TestEscapedSeparators(t *testing.T) {
+	resp, rest, err := MakeAlternativeFromString(`f=a\|b|g=c`, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "a|b", resp.Value)
+	assert.Equal(t, "g=c", rest)
+
+	resp, rest, err = MakeAlternativeFromString(`f=a\&b&g=c`, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "a&b", resp.Value)
+	assert.Equal(t, "&g=c", rest)
+}
@@ -0,0 +1,179 @@
+package runes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldBuilder starts building an alternative for a field, e.g.
+//
+//	runes.Field("method").HasPrefix("list").Or(runes.Field("method").Equals("getinfo"))
+type FieldBuilder struct {
+	name string
+}
+
+// Condition is a restriction being built (alternatives joined by OR)
+type Condition struct {
+	alternatives []Alternative
+	err          error
+}
+
+// Field returns a builder for field name
+func Field(name string) FieldBuilder {
+	return FieldBuilder{name: name}
+}
+
+func (f FieldBuilder) build(cond string, value string) *Condition {
+	// Field must parse the same way in lenient and strict mode
+	if f.name == "" || containsPunctuation(f.name, ParseOptions{}) || !utf8.ValidString(f.name) || f.name != strings.TrimSpace(f.name) {
+		return &Condition{err: newParseError(ParseBadField, 0, f.name, "field not valid")}
+	}
+	if !utf8.ValidString(value) {
+		return &Condition{err: newParseError(ParseBadUTF8, 0, value, "value is not valid UTF-8")}
+	}
+	// Restrictions are trimmed when parsed, so surrounding whitespace would not survive String()
+	if value != strings.TrimSpace(value) {
+		return &Condition{err: newParseError(ParseBadValue, 0, value, "value has leading or trailing whitespace")}
+	}
+
+	alt, err := MakeAlternative(f.name, cond, value, false)
+	if err != nil {
+		return &Condition{err: err}
+	}
+
+	return &Condition{alternatives: []Alternative{*alt}}
+}
+
+// Missing requires field to be absent (!)
+func (f FieldBuilder) Missing() *Condition {
+	return f.build("!", "")
+}
+
+// Equals requires field to be equal to value (=)
+func (f FieldBuilder) Equals(value string) *Condition {
+	return f.build("=", value)
+}
+
+// NotEquals requires field to be present and not equal to value (/)
+func (f FieldBuilder) NotEquals(value string) *Condition {
+	return f.build("/", value)
+}
+
+// HasPrefix requires field to start with value (^)
+func (f FieldBuilder) HasPrefix(value string) *Condition {
+	return f.build("^", value)
+}
+
+// HasSuffix requires field to end with value ($)
+func (f FieldBuilder) HasSuffix(value string) *Condition {
+	return f.build("$", value)
+}
+
+// Contains requires field to contain value (~)
+func (f FieldBuilder) Contains(value string) *Condition {
+	return f.build("~", value)
+}
+
+// LessThan requires field to be a number lower than value (<)
+func (f FieldBuilder) LessThan(value int64) *Condition {
+	return f.build("<", strconv.FormatInt(value, 10))
+}
+
+// GreaterThan requires field to be a number greater than value (>)
+func (f FieldBuilder) GreaterThan(value int64) *Condition {
+	return f.build(">", strconv.FormatInt(value, 10))
+}
+
+// SortsBefore requires field to be lexicographically ordered before value ({)
+func (f FieldBuilder) SortsBefore(value string) *Condition {
+	return f.build("{", value)
+}
+
+// SortsAfter requires field to be lexicographically ordered after value (})
+func (f FieldBuilder) SortsAfter(value string) *Condition {
+	return f.build("}", value)
+}
+
+// Comment is always satisfied (#)
+func (f FieldBuilder) Comment(value string) *Condition {
+	return f.build("#", value)
+}
+
+// Or returns a new condition that is satisfied when c or any of others is satisfied
+func (c *Condition) Or(others ...*Condition) *Condition {
+	ret := &Condition{
+		alternatives: append([]Alternative{}, c.alternatives...),
+		err:          c.err,
+	}
+
+	for _, other := range others {
+		if ret.err != nil {
+			break
+		}
+		if other == nil {
+			ret.err = fmt.Errorf("nil condition")
+			break
+		}
+		ret.err = other.err
+		ret.alternatives = append(ret.alternatives, other.alternatives...)
+	}
+
+	return ret
+}
+
+// Restriction returns the built restriction
+func (c *Condition) Restriction() (*Restriction, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return MakeRestriction(append([]Alternative{}, c.alternatives...))
+}
+
+// String returns a string representation
+func (c *Condition) String() string {
+	r, err := c.Restriction()
+	if err != nil {
+		return fmt.Sprintf("invalid condition: %v", err)
+	}
+
+	return r.String()
+}
+
+// Restrictions builds restrictions from conditions (joined by AND)
+func Restrictions(conditions ...*Condition) ([]Restriction, error) {
+	ret := make([]Restriction, 0, len(conditions))
+	for i, c := range conditions {
+		if c == nil {
+			return nil, fmt.Errorf("nil condition %d", i)
+		}
+		r, err := c.Restriction()
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, *r)
+	}
+
+	return ret, nil
+}
+
+// MustRestrictions builds restrictions from conditions and panics on error
+func MustRestrictions(conditions ...*Condition) []Restriction {
+	ret, err := Restrictions(conditions...)
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// Restrict obtains a restricted rune from conditions
+func (r *Rune) Restrict(conditions ...*Condition) (*Rune, error) {
+	restrictions, err := Restrictions(conditions...)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetRestricted(restrictions...)
+}
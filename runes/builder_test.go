@@ -0,0 +1,104 @@
+package runes
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilder(t *testing.T) {
+	cond := Field("method").HasPrefix("list").Or(Field("method").Equals("getinfo"))
+	assert.Equal(t, "method^list|method=getinfo", cond.String())
+
+	restrictions, err := Restrictions(cond, Field("time").LessThan(1700000000), Field("note").Equals("a&b|c\\"))
+	assert.NoError(t, err)
+	assert.Equal(t, MustMakeRestrictionsFromString(`method^list|method=getinfo&time<1700000000&note=a\&b\|c\\`), restrictions)
+
+	var secret [16]byte
+	master := MustMakeMasterRune(secret[:])
+	restricted, err := master.Restrict(cond, Field("time").LessThan(1700000000))
+	assert.NoError(t, err)
+	expected := master.MustGetRestrictedFromString("method^list|method=getinfo&time<1700000000")
+	assert.Equal(t, expected.String(), restricted.String())
+
+	// Builder output always parses back, also in strict mode
+	parsed, err := FromStringWithOptions(restricted.String(), ParseOptions{Strict: true})
+	assert.NoError(t, err)
+	assert.Equal(t, restricted.Restrictions, parsed.Restrictions)
+}
+
+func TestBuilderConditions(t *testing.T) {
+	f := Field("f")
+	conds := map[string]*Condition{
+		"!": f.Missing(),
+		"=": f.Equals("v"),
+		"/": f.NotEquals("v"),
+		"^": f.HasPrefix("v"),
+		"$": f.HasSuffix("v"),
+		"~": f.Contains("v"),
+		"<": f.LessThan(1),
+		">": f.GreaterThan(1),
+		"{": f.SortsBefore("v"),
+		"}": f.SortsAfter("v"),
+		"#": f.Comment("v"),
+	}
+
+	for _, cond := range KnownConditions {
+		c, ok := conds[cond]
+		assert.True(t, ok, cond)
+		r, err := c.Restriction()
+		assert.NoError(t, err)
+		assert.Equal(t, cond, r.Alternatives[0].Cond)
+	}
+}
+
+func TestBuilderRoundTrip(t *testing.T) {
+	restrictions := MustRestrictions(
+		Field("method").Equals("a b").Or(Field("method").HasPrefix("x\ty")),
+		Field("note").Comment("spaces  inside | and & too"),
+		Field("name").Equals("à😀"),
+		Field("empty").Equals(""),
+	)
+
+	str := make([]string, 0, len(restrictions))
+	for _, r := range restrictions {
+		str = append(str, r.String())
+	}
+	parsed, err := MakeRestrictionsFromString(strings.Join(str, "&"))
+	assert.NoError(t, err)
+	assert.Equal(t, restrictions, parsed)
+}
+
+func TestBuilderInvalid(t *testing.T) {
+	var pe *ParseError
+
+	for _, field := range []string{"", "me.thod", "a=b", "a¿b", "\xff"} {
+		_, err := Field(field).Equals("x").Restriction()
+		assert.True(t, errors.As(err, &pe), field)
+		assert.Equal(t, ParseBadField, pe.Kind, field)
+	}
+
+	_, err := Field("a").Equals("\xff").Restriction()
+	assert.True(t, errors.As(err, &pe))
+	assert.Equal(t, ParseBadUTF8, pe.Kind)
+
+	for _, value := range []string{" x", "x ", "\tx", "x\n", "x\u00a0", " "} {
+		_, err = Field("a").Equals(value).Restriction()
+		assert.True(t, errors.As(err, &pe), value)
+		assert.Equal(t, ParseBadValue, pe.Kind, value)
+	}
+	_, err = Field(" a").Equals("x").Restriction()
+	assert.True(t, errors.As(err, &pe))
+	assert.Equal(t, ParseBadField, pe.Kind)
+
+	// Error propagates through Or
+	_, err = Restrictions(Field("a").Equals("1").Or(Field("b.c").Equals("2")))
+	assert.ErrorIs(t, err, ErrInvalidRune)
+
+	_, err = Restrictions(Field("a").Equals("1"), nil)
+	assert.Error(t, err)
+
+	assert.Panics(t, func() { MustRestrictions(Field("").Missing()) })
+}
@@ -0,0 +1,101 @@
+package runes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// CoreLightning createrune takes (and showrune returns) restrictions as an array of arrays of strings,
+// e.g. [["method^list","method^get"],["time<1700000000"]]. Every string is one alternative in the same
+// (escaped) form as used in the rune string.
+
+// ToNestedArray converts restrictions to CoreLightning array-of-arrays form
+func ToNestedArray(restrictions []Restriction) [][]string {
+	ret := make([][]string, 0, len(restrictions))
+
+	for _, restriction := range restrictions {
+		alternatives := make([]string, 0, len(restriction.Alternatives))
+		for _, alt := range restriction.Alternatives {
+			alternatives = append(alternatives, alt.String())
+		}
+		ret = append(ret, alternatives)
+	}
+
+	return ret
+}
+
+// FromNestedArray converts CoreLightning array-of-arrays form to restrictions
+func FromNestedArray(arr [][]string) ([]Restriction, error) {
+	restrictions := make([]Restriction, 0, len(arr))
+
+	for i, alternatives := range arr {
+		if len(alternatives) < 1 {
+			pe := newParseError(ParseEmptyRestriction, 0, "", "restriction must have some alternative")
+			pe.Restriction = i
+			return nil, pe
+		}
+
+		alts := make([]Alternative, 0, len(alternatives))
+		for j, str := range alternatives {
+			alt, err := parseSingleAlternative(str, i == 0 && j == 0)
+			if err != nil {
+				var pe *ParseError
+				if errors.As(err, &pe) {
+					pe.Restriction = i
+					pe.Alternative = j
+				}
+				return nil, err
+			}
+			alts = append(alts, *alt)
+		}
+
+		restriction, err := checkRestriction(alts, i == 0)
+		if err != nil {
+			var pe *ParseError
+			if errors.As(err, &pe) {
+				pe.Restriction = i
+			}
+			return nil, err
+		}
+
+		restrictions = append(restrictions, *restriction)
+	}
+
+	return restrictions, nil
+}
+
+// parseSingleAlternative parses str which must contain exactly one alternative
+func parseSingleAlternative(str string, allowIDField bool) (*Alternative, error) {
+	_, _, err := MakeAlternativeFromString(str, allowIDField)
+	if err != nil {
+		return nil, err
+	}
+
+	// With a '|' sentinel appended nothing may remain, otherwise str contains an unescaped '|' or '&'
+	alt, rest, err := MakeAlternativeFromString(str+"|", allowIDField)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rest) > 0 {
+		offset := len(str) - len(rest)
+		if rest[0] == '&' {
+			offset++
+		}
+		return nil, newParseError(ParseBadEscape, offset, str[offset:offset+1], "alternative must be exactly one alternative ('|' and '&' must be escaped)")
+	}
+
+	return alt, nil
+}
+
+// MakeRestrictionsFromJSON creates restrictions from CoreLightning JSON array-of-arrays form
+func MakeRestrictionsFromJSON(data []byte) ([]Restriction, error) {
+	var arr [][]string
+	err := json.Unmarshal(data, &arr)
+	if err != nil {
+		return nil, fmt.Errorf("restrictions must be an array of arrays of strings: %v %w", err, ErrInvalidRune)
+	}
+
+	return FromNestedArray(arr)
+}
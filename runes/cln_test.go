@@ -0,0 +1,74 @@
+package runes
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNestedArrayRoundTrip(t *testing.T) {
+	restrictions := MustMakeRestrictionsFromString(`=3&method^list|method^get&time<1700000000&note=a\&b\|c\\d`)
+
+	arr := ToNestedArray(restrictions)
+	assert.Equal(t, [][]string{{"=3"}, {"method^list", "method^get"}, {"time<1700000000"}, {`note=a\&b\|c\\d`}}, arr)
+
+	parsed, err := FromNestedArray(arr)
+	assert.NoError(t, err)
+	assert.Equal(t, restrictions, parsed)
+	assert.Equal(t, "a&b|c\\d", parsed[3].Alternatives[0].Value)
+
+	b, err := json.Marshal(arr)
+	assert.NoError(t, err)
+	fromJSON, err := MakeRestrictionsFromJSON(b)
+	assert.NoError(t, err)
+	assert.Equal(t, restrictions, fromJSON)
+}
+
+func TestNestedArrayCreateRune(t *testing.T) {
+	var secret [16]byte
+	master := MustMakeMasterRune(secret[:])
+
+	restrictions, err := MakeRestrictionsFromJSON([]byte(`[["method^list","method^get"],["time<1700000000"]]`))
+	assert.NoError(t, err)
+
+	fromArray, err := master.GetRestricted(restrictions...)
+	assert.NoError(t, err)
+	fromString := master.MustGetRestrictedFromString("method^list|method^get&time<1700000000")
+	assert.Equal(t, fromString.String(), fromArray.String())
+}
+
+func TestNestedArrayInvalid(t *testing.T) {
+	var pe *ParseError
+
+	for _, c := range []struct {
+		arr         [][]string
+		kind        ParseErrorKind
+		restriction int
+		alternative int
+		offset      int
+	}{
+		{[][]string{{"a=1"}, {}}, ParseEmptyRestriction, 1, -1, 0},
+		{[][]string{{"a=1"}, {"b=2", "c=3|d=4"}}, ParseBadEscape, 1, 1, 3},
+		{[][]string{{"a=1&b=2"}}, ParseBadEscape, 0, 0, 3},
+		{[][]string{{"a=1|"}}, ParseBadEscape, 0, 0, 3},
+		{[][]string{{`a=1\`}}, ParseBadEscape, 0, 0, 3},
+		{[][]string{{"a@1"}}, ParseUnknownOperator, 0, 0, 1},
+		{[][]string{{"a=1"}, {"=2"}}, ParseMisplacedUniqueID, 1, 0, 0},
+		{[][]string{{"=1", "a=2"}}, ParseMisplacedUniqueID, 0, 1, 0},
+	} {
+		_, err := FromNestedArray(c.arr)
+		assert.ErrorIs(t, err, ErrInvalidRune, c.arr)
+		if !assert.True(t, errors.As(err, &pe), c.arr) {
+			continue
+		}
+		assert.Equal(t, c.kind, pe.Kind, c.arr)
+		assert.Equal(t, c.restriction, pe.Restriction, c.arr)
+		assert.Equal(t, c.alternative, pe.Alternative, c.arr)
+		assert.Equal(t, c.offset, pe.Offset, c.arr)
+	}
+
+	_, err := MakeRestrictionsFromJSON([]byte(`["method^list"]`))
+	assert.ErrorIs(t, err, ErrInvalidRune)
+}
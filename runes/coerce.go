@@ -0,0 +1,415 @@
+package runes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+// ErrTypeMismatch represents an error where a value cannot be compared with a restriction in strict mode
+var ErrTypeMismatch = errors.New("type mismatch")
+
+// EvalOptions control evaluation.
+//
+// Values are coerced before they are compared with restriction literals (which are always strings):
+//
+//   - string, []byte and json.Number are used as text.
+//   - Integers (any size, including *big.Int) are decimal numbers.
+//   - Floats are formatted without exponent when they hold an integer below 1e21 (so 1e6 equals "1000000"),
+//     otherwise in the shortest form; integral floats compare as integers.
+//   - *big.Float holding an integer is an integer, otherwise it is approximated as float64.
+//   - time.Duration is a number of seconds (fractional when needed).
+//   - time.Time is a UNIX timestamp in seconds (fractional when needed), like the well-known time field.
+//   - bool is "true" or "false" and is never a number.
+//   - fmt.Stringer types use String(), anything else is formatted with %v.
+//
+// Operators =, /, ^, $, ~, { and } work on the text form. Operators < and > parse text as an integer (of any size)
+// or a float and compare numbers exactly, NaN and non-numeric values never pass.
+//
+// In strict mode (EvalOptions.Strict) mismatches are evaluation errors (ErrTypeMismatch) instead of silent denials:
+// < and > with a non-numeric value or literal, = and / comparing a number or bool with a literal of a different
+// kind and text operators on values without a text form.
+type EvalOptions struct {
+	// Strict turns type mismatches into evaluation errors
+	Strict bool
+}
+
+// TypeError is returned in strict mode when value cannot be compared with an alternative
+type TypeError struct {
+	Field    string
+	Operator string
+	Reason   string
+}
+
+// Error returns the error message
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("%v for %s%s: %s", ErrTypeMismatch, e.Field, e.Operator, e.Reason)
+}
+
+// Unwrap makes errors.Is(err, ErrTypeMismatch) work
+func (e *TypeError) Unwrap() error {
+	return ErrTypeMismatch
+}
+
+// valueKind is the kind of a coerced value
+type valueKind int
+
+const (
+	kindOther valueKind = iota
+	kindText
+	kindNumber
+	kindBool
+)
+
+type numberKind int
+
+const (
+	numberInt numberKind = iota
+	numberBig
+	numberFloat
+)
+
+// number is a coerced numeric value
+type number struct {
+	kind numberKind
+	i    int64
+	b    *big.Int
+	f    float64
+}
+
+func intNumber(i int64) number {
+	return number{kind: numberInt, i: i}
+}
+
+func bigNumber(b *big.Int) number {
+	if b.IsInt64() {
+		return intNumber(b.Int64())
+	}
+	return number{kind: numberBig, b: b}
+}
+
+func floatNumber(f float64) number {
+	// Conversion is exact for integral floats in range
+	if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+		return intNumber(int64(f))
+	}
+	return number{kind: numberFloat, f: f}
+}
+
+func (n number) bigFloat() *big.Float {
+	switch n.kind {
+	case numberInt:
+		return new(big.Float).SetInt64(n.i)
+	case numberBig:
+		return new(big.Float).SetInt(n.b)
+	default:
+		return new(big.Float).SetFloat64(n.f)
+	}
+}
+
+// exactFloat returns n as float64 when the conversion is exact
+func (n number) exactFloat() (float64, bool) {
+	switch n.kind {
+	case numberInt:
+		return float64(n.i), n.i >= -maxExactFloat && n.i <= maxExactFloat
+	case numberFloat:
+		return n.f, true
+	default:
+		return 0, false
+	}
+}
+
+func (n number) bigInt() *big.Int {
+	if n.kind == numberBig {
+		return n.b
+	}
+	return big.NewInt(n.i)
+}
+
+// compareNumbers compares two numbers exactly, ok is false when they are not ordered (NaN)
+func compareNumbers(a, b number) (int, bool) {
+	if a.kind == numberInt && b.kind == numberInt {
+		return compareOrdered(a.i < b.i, a.i > b.i), true
+	}
+	if a.kind != numberFloat && b.kind != numberFloat {
+		return a.bigInt().Cmp(b.bigInt()), true
+	}
+	if (a.kind == numberFloat && math.IsNaN(a.f)) || (b.kind == numberFloat && math.IsNaN(b.f)) {
+		return 0, false
+	}
+	if fa, ok := a.exactFloat(); ok {
+		if fb, ok := b.exactFloat(); ok {
+			return compareOrdered(fa < fb, fa > fb), true
+		}
+	}
+	return a.bigFloat().Cmp(b.bigFloat()), true
+}
+
+// isDecimal reports whether s is an optionally signed sequence of decimal digits
+func isDecimal(s string) bool {
+	if len(s) > 0 && (s[0] == '-' || s[0] == '+') {
+		s = s[1:]
+	}
+	if len(s) == 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseNumber parses text as an integer (of any size) or a float
+func parseNumber(s string) (number, bool) {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return intNumber(i), true
+	}
+	if isDecimal(s) {
+		b, ok := new(big.Int).SetString(s, 10)
+		if ok {
+			return number{kind: numberBig, b: b}, true
+		}
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return number{kind: numberFloat, f: f}, true
+	}
+	return number{}, false
+}
+
+func formatFloat(f float64, bitSize int) string {
+	if f == math.Trunc(f) && math.Abs(f) < 1e21 {
+		return strconv.FormatFloat(f, 'f', -1, bitSize)
+	}
+	return strconv.FormatFloat(f, 'g', -1, bitSize)
+}
+
+func durationNumber(d time.Duration) number {
+	if d%time.Second == 0 {
+		return intNumber(int64(d / time.Second))
+	}
+	return number{kind: numberFloat, f: d.Seconds()}
+}
+
+func timeNumber(t time.Time) number {
+	if t.Nanosecond() == 0 {
+		return intNumber(t.Unix())
+	}
+	return number{kind: numberFloat, f: float64(t.Unix()) + float64(t.Nanosecond())/1e9}
+}
+
+func (n number) String() string {
+	switch n.kind {
+	case numberInt:
+		return strconv.FormatInt(n.i, 10)
+	case numberBig:
+		return n.b.String()
+	default:
+		return formatFloat(n.f, 64)
+	}
+}
+
+// coerceNumber returns the numeric form of v and its kind
+func coerceNumber(v any) (number, valueKind, bool) {
+	switch val := v.(type) {
+	case string:
+		n, ok := parseNumber(val)
+		return n, kindText, ok
+	case []byte:
+		n, ok := parseNumber(string(val))
+		return n, kindText, ok
+	case json.Number:
+		n, ok := parseNumber(string(val))
+		return n, kindNumber, ok
+	case bool:
+		return number{}, kindBool, false
+	case int:
+		return intNumber(int64(val)), kindNumber, true
+	case int8:
+		return intNumber(int64(val)), kindNumber, true
+	case int16:
+		return intNumber(int64(val)), kindNumber, true
+	case int32:
+		return intNumber(int64(val)), kindNumber, true
+	case int64:
+		return intNumber(val), kindNumber, true
+	case uint:
+		return bigNumber(new(big.Int).SetUint64(uint64(val))), kindNumber, true
+	case uint8:
+		return intNumber(int64(val)), kindNumber, true
+	case uint16:
+		return intNumber(int64(val)), kindNumber, true
+	case uint32:
+		return intNumber(int64(val)), kindNumber, true
+	case uint64:
+		if val <= math.MaxInt64 {
+			return intNumber(int64(val)), kindNumber, true
+		}
+		return bigNumber(new(big.Int).SetUint64(val)), kindNumber, true
+	case float32:
+		return floatNumber(float64(val)), kindNumber, true
+	case float64:
+		return floatNumber(val), kindNumber, true
+	case *big.Int:
+		if val == nil {
+			return number{}, kindOther, false
+		}
+		return bigNumber(val), kindNumber, true
+	case big.Int:
+		return bigNumber(&val), kindNumber, true
+	case *big.Float:
+		if val == nil {
+			return number{}, kindOther, false
+		}
+		if val.IsInt() && !val.IsInf() {
+			b, _ := val.Int(nil)
+			return bigNumber(b), kindNumber, true
+		}
+		f, _ := val.Float64()
+		return number{kind: numberFloat, f: f}, kindNumber, true
+	case time.Duration:
+		return durationNumber(val), kindNumber, true
+	case time.Time:
+		return timeNumber(val), kindNumber, true
+	default:
+		str, kind, _ := coerceString(v)
+		n, ok := parseNumber(str)
+		return n, kind, ok
+	}
+}
+
+// coerceString returns the text form of v, ok is false when v has none and %v was used
+func coerceString(v any) (string, valueKind, bool) {
+	switch val := v.(type) {
+	case string:
+		return val, kindText, true
+	case []byte:
+		return string(val), kindText, true
+	case json.Number:
+		return string(val), kindNumber, true
+	case bool:
+		return strconv.FormatBool(val), kindBool, true
+	case int:
+		return strconv.FormatInt(int64(val), 10), kindNumber, true
+	case int64:
+		return strconv.FormatInt(val, 10), kindNumber, true
+	case float32:
+		return formatFloat(float64(val), 32), kindNumber, true
+	case float64:
+		return formatFloat(val, 64), kindNumber, true
+	case *big.Float:
+		if val == nil {
+			return fmt.Sprintf("%v", v), kindOther, false
+		}
+		if val.IsInt() && !val.IsInf() {
+			b, _ := val.Int(nil)
+			return b.String(), kindNumber, true
+		}
+		return val.Text('g', -1), kindNumber, true
+	case int8, int16, int32, uint, uint8, uint16, uint32, uint64, *big.Int, big.Int, time.Duration, time.Time:
+		n, kind, ok := coerceNumber(v)
+		if !ok {
+			return fmt.Sprintf("%v", v), kindOther, false
+		}
+		return n.String(), kind, true
+	case fmt.Stringer:
+		return val.String(), kindOther, true
+	default:
+		return fmt.Sprintf("%v", v), kindOther, false
+	}
+}
+
+// valueString returns the text form of v
+func valueString(v any) string {
+	str, _, _ := coerceString(v)
+	return str
+}
+
+// compareValues compares a and b numerically, ok is false when they are not comparable
+func compareValues(a, b any) (int, bool) {
+	numA, _, ok := coerceNumber(a)
+	if !ok {
+		return 0, false
+	}
+	numB, _, ok := coerceNumber(b)
+	if !ok {
+		return 0, false
+	}
+	return compareNumbers(numA, numB)
+}
+
+func compareOrdered(lower, higher bool) int {
+	if lower {
+		return -1
+	}
+	if higher {
+		return 1
+	}
+	return 0
+}
+
+// Wake me up when golang gets better generics, until then we do some ugly hacks with "any" (I'd rather use comparable and constraints.Ordered)
+
+func isLower(a, b any) (bool, error) {
+	cmp, ok := compareValues(a, b)
+	if !ok {
+		return false, fmt.Errorf("could not compare")
+	}
+	return cmp < 0, nil
+}
+
+func isHigher(a, b any) (bool, error) {
+	cmp, ok := compareValues(a, b)
+	if !ok {
+		return false, fmt.Errorf("could not compare")
+	}
+	return cmp > 0, nil
+}
+
+func isEqual(a, b any) (bool, error) {
+	return valueString(a) == valueString(b), nil
+}
+
+// typeMismatch returns an error when actual cannot be compared with literal using cond in strict mode,
+// numeric tells whether literal is a number
+func typeMismatch(field, cond string, actual any, literal string, numeric bool) error {
+	mismatch := func(format string, args ...any) error {
+		return &TypeError{Field: field, Operator: cond, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	switch cond {
+	case "<", ">":
+		if _, kind, ok := coerceNumber(actual); !ok {
+			if kind == kindText {
+				return mismatch("value is not a number")
+			}
+			return mismatch("value of type %T is not a number", actual)
+		}
+		if !numeric {
+			return mismatch("literal %q is not a number", literal)
+		}
+	case "=", "/", "^", "$", "~", "{", "}":
+		_, kind, ok := coerceString(actual)
+		if !ok {
+			return mismatch("value of type %T has no text form", actual)
+		}
+		if cond != "=" && cond != "/" {
+			return nil
+		}
+		if kind == kindNumber {
+			if !numeric {
+				return mismatch("cannot compare number with %q", literal)
+			}
+		}
+		if kind == kindBool && literal != "true" && literal != "false" {
+			return mismatch("cannot compare bool with %q", literal)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package runes
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testStringer struct{}
+
+func (testStringer) String() string { return "stringer" }
+
+func TestValueString(t *testing.T) {
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+
+	for _, c := range []struct {
+		value    any
+		expected string
+	}{
+		{"abc", "abc"},
+		{[]byte("abc"), "abc"},
+		{json.Number("1.50"), "1.50"},
+		{true, "true"},
+		{-5, "-5"},
+		{int8(-5), "-5"},
+		{uint64(math.MaxUint64), "18446744073709551615"},
+		{1e6, "1000000"},
+		{3.0, "3"},
+		{1.5, "1.5"},
+		{1e21, "1e+21"},
+		{float32(0.1), "0.1"},
+		{math.Inf(1), "+Inf"},
+		{huge, "123456789012345678901234567890"},
+		{*huge, "123456789012345678901234567890"},
+		{big.NewFloat(2.5), "2.5"},
+		{big.NewFloat(1e6), "1000000"},
+		{90 * time.Second, "90"},
+		{1500 * time.Millisecond, "1.5"},
+		{time.Unix(1674742049, 0), "1674742049"},
+		{time.Unix(100, 5e8), "100.5"},
+		{testStringer{}, "stringer"},
+		{nil, "<nil>"},
+		{[]int{1, 2}, "[1 2]"},
+	} {
+		assert.Equal(t, c.expected, valueString(c.value), "%#v", c.value)
+		assert.Equal(t, c.expected, stringValue(c.value), "%#v", c.value)
+	}
+}
+
+func TestCompareValues(t *testing.T) {
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+
+	for _, c := range []struct {
+		a, b     any
+		expected int
+		ok       bool
+	}{
+		{1e6, "1000001", -1, true},
+		{"1e6", "1000000", 0, true},
+		{uint64(math.MaxUint64), "18446744073709551616", -1, true},
+		{int64(1<<60 + 1), "1152921504606846976", 1, true},
+		{int64(1<<60 + 1), "1152921504606846976.0", 1, true},
+		{huge, "1e29", 1, true},
+		{huge, "123456789012345678901234567891", -1, true},
+		{big.NewFloat(2.5), "2", 1, true},
+		{json.Number("12"), "13", -1, true},
+		{[]byte("5"), "6", -1, true},
+		{time.Unix(99, 5e8), "100", -1, true},
+		{time.Unix(100, 5e8), "100", 1, true},
+		{2 * time.Minute, "119", 1, true},
+		{"1.5", "1", 1, true},
+		{"NaN", "1", 0, false},
+		{true, "1", 0, false},
+		{"abc", "1", 0, false},
+		{1, "abc", 0, false},
+		{(*big.Int)(nil), "1", 0, false},
+	} {
+		cmp, ok := compareValues(c.a, c.b)
+		assert.Equal(t, c.ok, ok, "%#v %v", c.a, c.b)
+		assert.Equal(t, c.expected, cmp, "%#v %v", c.a, c.b)
+	}
+}
+
+func TestLexicographicObtainValue(t *testing.T) {
+	alt, err := MakeAlternative("f", "}", "b", false)
+	assert.NoError(t, err)
+
+	ok, _ := alt.Evaluate(map[string]any{"f": ObtainValue(func() any { return "c" })})
+	assert.True(t, ok)
+
+	ok, msg := alt.Evaluate(map[string]any{"f": ObtainValue(func() any { return "a" })})
+	assert.False(t, ok)
+	assert.Equal(t, "is the same or ordered before b", msg)
+}
+
+func TestCoerceCompiled(t *testing.T) {
+	r, err := FromAuthCode(make([]byte, 32), MustMakeRestrictionsFromString("v=1000000|v=true|v<100.5&v>99|v}a|v{1|v~5|v=12"))
+	assert.NoError(t, err)
+	evaluator := r.Compile()
+
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	for _, v := range []any{
+		1e6, true, false, time.Unix(100, 5e8), 100 * time.Second, json.Number("12"), []byte("b"), huge,
+		big.NewFloat(99.5), uint8(100), float32(100.25), testStringer{}, ObtainValue(func() any { return "z" }),
+	} {
+		vals := map[string]any{"v": v}
+		expectedOK, expectedMsg := r.Evaluate(vals)
+		ok, msg := evaluator.Evaluate(vals)
+		assert.Equal(t, expectedOK, ok, "%#v", v)
+		assert.Equal(t, expectedMsg, msg, "%#v", v)
+	}
+}
+
+func TestStrict(t *testing.T) {
+	strict := EvalOptions{Strict: true}
+
+	for _, c := range []struct {
+		restrictions string
+		value        any
+		ok           bool // result without strict mode
+		mismatch     bool
+	}{
+		{"v<100", 50, true, false},
+		{"v<100", "50", true, false},
+		{"v<100", time.Unix(50, 0), true, false},
+		{"v<100", 150, false, false},
+		{"v<100", "abc", false, true},
+		{"v<100", true, false, true},
+		{"v<100", []int{1}, false, true},
+		{"v<abc", 50, false, true},
+		{"v=5", 5, true, false},
+		{"v=abc", 5, false, true},
+		{"v=abc", "5", false, false},
+		{"v=true", true, true, false},
+		{"v=yes", true, false, true},
+		{"v^a", struct{}{}, false, true},
+		{"v^a", testStringer{}, false, false},
+		{"v!", 5, false, false},
+		{"v!|v<abc", "x", false, true},
+		{"v<abc|v=x", "x", true, true},
+		{"v=x|v<abc", "x", true, false},
+	} {
+		r, err := FromAuthCode(make([]byte, 32), MustMakeRestrictionsFromString(c.restrictions))
+		assert.NoError(t, err)
+		vals := map[string]any{"v": c.value}
+
+		result, err := r.EvaluateWithOptions(vals, strict)
+		if c.mismatch {
+			assert.ErrorIs(t, err, ErrTypeMismatch, "%s %#v", c.restrictions, c.value)
+			assert.Equal(t, OutcomeError, result.Outcome)
+
+			// Without strict mode mismatches are silent denials
+			ok, _ := r.Evaluate(vals)
+			assert.Equal(t, c.ok, ok, "%s %#v", c.restrictions, c.value)
+			continue
+		}
+
+		assert.NoError(t, err, "%s %#v", c.restrictions, c.value)
+		assert.Equal(t, c.ok, result.OK(), "%s %#v", c.restrictions, c.value)
+		ok, _ := r.Evaluate(vals)
+		assert.Equal(t, c.ok, ok, "%s %#v", c.restrictions, c.value)
+	}
+
+	r, err := FromAuthCode(make([]byte, 32), MustMakeRestrictionsFromString("v<100"))
+	assert.NoError(t, err)
+	assert.EqualError(t, r.CheckWithOptions(map[string]any{"v": "abc"}, strict), `type mismatch for v<: value is not a number`)
+	assert.EqualError(t, r.CheckWithOptions(map[string]any{"v": "150"}, strict), ">= 100")
+	assert.NoError(t, r.CheckWithOptions(map[string]any{"v": "50"}, strict))
+}
@@ -0,0 +1,269 @@
+package runes
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxInlineFields is the number of distinct fields an evaluation remembers without allocations
+const maxInlineFields = 8
+
+// Evaluator is a compiled form of rune restrictions. It gives exactly the same results as the rune (Evaluate,
+// Check and CheckContext) but literals are parsed once, so evaluating string values and numeric comparisons does not
+// allocate unless it fails. Evaluator is immutable and safe for concurrent use.
+type Evaluator struct {
+	compiled     [][]compiledAlternative
+	restrictions []Restriction
+}
+
+type compiledAlternative struct {
+	alt Alternative
+	// literal is the string form of alternative value
+	literal  string
+	litNum   number
+	litNumOK bool
+	// missingOK is the result when field is missing
+	missingOK bool
+	test      func(c *compiledAlternative, actual any) bool
+}
+
+// Compile returns an evaluator for restrictions of the rune
+func (r *Rune) Compile() *Evaluator {
+	ret := &Evaluator{
+		compiled:     make([][]compiledAlternative, 0, len(r.Restrictions)),
+		restrictions: append([]Restriction(nil), r.Restrictions...),
+	}
+	for _, restriction := range r.Restrictions {
+		alternatives := make([]compiledAlternative, 0, len(restriction.Alternatives))
+		for _, alt := range restriction.Alternatives {
+			alternatives = append(alternatives, compileAlternative(alt))
+		}
+		ret.compiled = append(ret.compiled, alternatives)
+	}
+
+	return ret
+}
+
+func compileAlternative(alt Alternative) compiledAlternative {
+	ret := compiledAlternative{alt: alt, literal: fmt.Sprintf("%v", alt.Value), missingOK: alt.Cond == "!"}
+	ret.litNum, ret.litNumOK = parseNumber(ret.literal)
+
+	if alt.IsUniqueID() {
+		if s, ok := alt.Value.(string); !ok || strings.Contains(s, "-") {
+			ret.missingOK = false
+		}
+	}
+
+	switch alt.Cond {
+	case "=":
+		ret.test = func(c *compiledAlternative, actual any) bool { return stringValue(actual) == c.literal }
+	case "/":
+		ret.test = func(c *compiledAlternative, actual any) bool { return stringValue(actual) != c.literal }
+	case "^":
+		ret.test = func(c *compiledAlternative, actual any) bool {
+			return strings.HasPrefix(stringValue(actual), c.literal)
+		}
+	case "$":
+		ret.test = func(c *compiledAlternative, actual any) bool {
+			return strings.HasSuffix(stringValue(actual), c.literal)
+		}
+	case "~":
+		ret.test = func(c *compiledAlternative, actual any) bool {
+			return strings.Contains(stringValue(actual), c.literal)
+		}
+	case "<":
+		ret.test = func(c *compiledAlternative, actual any) bool {
+			cmp, ok := c.compare(actual)
+			return ok && cmp < 0
+		}
+	case ">":
+		ret.test = func(c *compiledAlternative, actual any) bool {
+			cmp, ok := c.compare(actual)
+			return ok && cmp > 0
+		}
+	case "{":
+		ret.test = func(c *compiledAlternative, actual any) bool { return stringValue(actual) < c.literal }
+	case "}":
+		ret.test = func(c *compiledAlternative, actual any) bool { return stringValue(actual) > c.literal }
+	default:
+		// ! always fails when field is present, # never gets here and unknown conditions always fail
+		ret.test = func(c *compiledAlternative, actual any) bool { return false }
+	}
+
+	return ret
+}
+
+// stringValue returns the same as valueString without allocating for common types
+func stringValue(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case int:
+		return strconv.FormatInt(int64(val), 10)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case bool:
+		return strconv.FormatBool(val)
+	default:
+		return valueString(v)
+	}
+}
+
+// compare compares actual with the literal like isLower and isHigher
+func (c *compiledAlternative) compare(actual any) (int, bool) {
+	if !c.litNumOK {
+		return 0, false
+	}
+
+	var num number
+	switch val := actual.(type) {
+	case int:
+		num = intNumber(int64(val))
+	case int64:
+		num = intNumber(val)
+	case string:
+		var ok bool
+		num, ok = parseNumber(val)
+		if !ok {
+			return 0, false
+		}
+	default:
+		var ok bool
+		num, _, ok = coerceNumber(actual)
+		if !ok {
+			return 0, false
+		}
+	}
+
+	return compareNumbers(num, c.litNum)
+}
+
+// evaluate reports whether alternative passes, sure is false when the result depends on an error (which only
+// full evaluation explains)
+func (c *compiledAlternative) evaluate(cache *fieldCache, opts EvalOptions) (pass bool, sure bool) {
+	if c.alt.Cond == "#" {
+		return true, true
+	}
+
+	actual, ok, err := cache.lookup(c.alt.Field)
+	if err != nil {
+		return false, false
+	}
+	if !ok {
+		return c.missingOK, true
+	}
+	if opts.Strict && typeMismatch(c.alt.Field, c.alt.Cond, actual, c.literal, c.litNumOK) != nil {
+		return false, false
+	}
+
+	return c.test(c, actual), true
+}
+
+// cachedField is a looked up value
+type cachedField struct {
+	field string
+	value any
+	ok    bool
+	err   error
+}
+
+// fieldCache resolves every field at most once like contextLookup but without allocations for a few fields
+type fieldCache struct {
+	ctx    context.Context
+	vals   Values
+	inline [maxInlineFields]cachedField
+	n      int
+	more   map[string]cachedField
+}
+
+func (c *fieldCache) lookup(field string) (any, bool, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	for i := 0; i < c.n; i++ {
+		if c.inline[i].field == field {
+			return c.inline[i].value, c.inline[i].ok, c.inline[i].err
+		}
+	}
+	if one, ok := c.more[field]; ok {
+		return one.value, one.ok, one.err
+	}
+
+	one := cachedField{field: field}
+	one.value, one.ok, one.err = resolve(c.ctx, field, c.vals)
+	if c.n < len(c.inline) {
+		c.inline[c.n] = one
+		c.n++
+	} else {
+		if c.more == nil {
+			c.more = make(map[string]cachedField)
+		}
+		c.more[field] = one
+	}
+
+	return one.value, one.ok, one.err
+}
+
+// passes reports whether every restriction certainly passes
+func (e *Evaluator) passes(cache *fieldCache, opts EvalOptions) bool {
+	for _, alternatives := range e.compiled {
+		passed := false
+		for i := range alternatives {
+			pass, sure := alternatives[i].evaluate(cache, opts)
+			if !sure {
+				return false
+			}
+			if pass {
+				passed = true
+				break
+			}
+		}
+		if !passed {
+			return false
+		}
+	}
+
+	return true
+}
+
+// evaluateResult evaluates the restrictions in full reusing already resolved values
+func (e *Evaluator) evaluateResult(cache fieldCache, opts EvalOptions) *EvalResult {
+	return evaluateAll(e.restrictions, cache.lookup, opts)
+}
+
+// Evaluate evaluates the compiled rune
+func (e *Evaluator) Evaluate(vals map[string]any) (bool, string) {
+	cache := fieldCache{ctx: context.Background(), vals: MapValues(vals)}
+	if e.passes(&cache, EvalOptions{}) {
+		return true, ""
+	}
+
+	result := e.evaluateResult(cache, EvalOptions{})
+	return result.OK(), result.Reason()
+}
+
+// Check checks the compiled rune, it returns *EvalError when rune is denied
+func (e *Evaluator) Check(vals map[string]any) error {
+	return e.CheckContext(context.Background(), MapValues(vals), EvalOptions{})
+}
+
+// CheckContext checks the compiled rune like Rune.CheckContext
+func (e *Evaluator) CheckContext(ctx context.Context, vals Values, opts EvalOptions) error {
+	cache := fieldCache{ctx: ctx, vals: vals}
+	if e.passes(&cache, opts) {
+		return nil
+	}
+
+	result := e.evaluateResult(cache, opts)
+	if result.Err != nil {
+		return result.Err
+	}
+	if !result.OK() {
+		return &EvalError{Result: result}
+	}
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package runes
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func compileValues() []map[string]any {
+	obtained := 0
+	return []map[string]any{
+		{},
+		{"method": "listpeers", "time": 1674742049, "pnum": 1},
+		{"method": "getinfo", "time": "1674742049", "pnum": "1.5"},
+		{"method": "", "time": int64(-5), "pnum": uint64(math.MaxUint64)},
+		{"method": true, "time": 1.5, "pnum": int32(3), "id": uint(7)},
+		{"method": "pay", "time": ObtainValue(func() any { obtained++; return obtained })},
+		{"method": ObtainValue(func() any { return "listfunds" }), "pnum": "1e3", "": "1-2"},
+		{"": "1", "time": "NaN", "pnum": "abc", "id": []byte("x")},
+		{"method": ObtainValueCtx(func(ctx context.Context) (any, bool, error) { return "pay", true, nil }), "time": 5},
+		{"method": "getinfo", "pnum": ObtainValueCtx(func(ctx context.Context) (any, bool, error) { return nil, false, nil })},
+		{"method": "listpeers", "time": ObtainValueCtx(func(ctx context.Context) (any, bool, error) { return nil, false, errors.New("down") })},
+	}
+}
+
+// assertSameError checks that errors have the same type and message
+func assertSameError(t *testing.T, expected, actual error, msgAndArgs ...any) {
+	assert.IsType(t, expected, actual, msgAndArgs...)
+	if expected != nil && actual != nil {
+		assert.Equal(t, expected.Error(), actual.Error(), msgAndArgs...)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	for _, str := range []string{
+		"",
+		"method^list|method^get&time<1674742050",
+		"method=pay|method/getinfo&method$peers|method~info",
+		"time>1674742048&time<1.7e9&pnum<2|pnum>1e3|pnum=1",
+		"method{m&method}g|method!",
+		"pnum<NaN|pnum>-1|pnum<18446744073709551616|pnum>18446744073709551614",
+		"pnum!|id=7|id}6&note#comment",
+		"time<abc|time>abc|method{|method}",
+		"method/pay&time<10|pnum!",
+		"method=pay|method^list&time>-1|method=listpeers",
+	} {
+		restrictions, err := MakeRestrictionsFromString(str)
+		assert.NoError(t, err, str)
+
+		r, err := FromAuthCode(make([]byte, 32), restrictions)
+		assert.NoError(t, err)
+		evaluator := r.Compile()
+
+		for _, vals := range compileValues() {
+			expectedOK, expectedMsg := r.Evaluate(vals)
+			ok, msg := evaluator.Evaluate(vals)
+			assert.Equal(t, expectedOK, ok, "%s %v", str, vals)
+			assert.Equal(t, expectedMsg, msg, "%s %v", str, vals)
+			assertSameError(t, r.Check(vals), evaluator.Check(vals), "%s %v", str, vals)
+
+			strict := EvalOptions{Strict: true}
+			expected := r.CheckContext(context.Background(), MapValues(vals), strict)
+			assertSameError(t, expected, evaluator.CheckContext(context.Background(), MapValues(vals), strict), "%s %v", str, vals)
+		}
+	}
+}
+
+func TestCompileCheck(t *testing.T) {
+	r, err := FromAuthCode(make([]byte, 32), MustMakeRestrictionsFromString("method^list&time<100"))
+	assert.NoError(t, err)
+	evaluator := r.Compile()
+
+	err = evaluator.Check(map[string]any{"method": "pay"})
+	var evalErr *EvalError
+	if assert.True(t, errors.As(err, &evalErr)) {
+		assert.Equal(t, 0, evalErr.Result.Failed())
+	}
+	assert.EqualError(t, err, "does not start with list")
+
+	vals := MapValues{"method": "listpeers", "time": "abc"}
+	assert.ErrorAs(t, evaluator.Check(vals), &evalErr)
+	assert.ErrorIs(t, evaluator.CheckContext(context.Background(), vals, EvalOptions{Strict: true}), ErrTypeMismatch)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, evaluator.CheckContext(ctx, MapValues{"method": "listpeers", "time": 1}, EvalOptions{}), context.Canceled)
+
+	// Lazy values are resolved once even when evaluation falls back to explain a failure
+	calls := 0
+	lazy := map[string]any{"method": ObtainValue(func() any { calls++; return "pay" })}
+	assert.Error(t, evaluator.Check(lazy))
+	assert.Equal(t, 1, calls)
+}
+
+func TestCompileUniqueID(t *testing.T) {
+	for _, id := range []any{"1", "1-2", 5} {
+		r := &Rune{Restrictions: []Restriction{{Alternatives: []Alternative{{Field: "", Cond: "=", Value: id}}}}}
+		for _, vals := range []map[string]any{{}, {"": "1"}, {"": "1-2"}} {
+			expectedOK, expectedMsg := r.Evaluate(vals)
+			ok, msg := r.Compile().Evaluate(vals)
+			assert.Equal(t, expectedOK, ok)
+			assert.Equal(t, expectedMsg, msg)
+		}
+	}
+
+	r := &Rune{Restrictions: []Restriction{{Alternatives: []Alternative{{Field: "method", Cond: "?", Value: "x"}}}}}
+	assert.EqualError(t, r.Compile().Check(map[string]any{"method": "x"}), "unhandled case: ?")
+}
+
+func TestCompileAllocations(t *testing.T) {
+	r, err := FromAuthCode(make([]byte, 32), MustMakeRestrictionsFromString("method^list|method^get|method=summary&method/listdatastore&time<1674742050&pnum>-1.5&id{z"))
+	assert.NoError(t, err)
+	evaluator := r.Compile()
+
+	vals := map[string]any{"method": "summary", "time": 1674742049, "pnum": "3", "id": int64(42)}
+	ok, _ := evaluator.Evaluate(vals)
+	assert.True(t, ok)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		evaluator.Evaluate(vals)
+	})
+	assert.Equal(t, 0.0, allocs)
+
+	strict := EvalOptions{Strict: true}
+	assert.NoError(t, evaluator.CheckContext(context.Background(), MapValues(vals), strict))
+	allocs = testing.AllocsPerRun(100, func() {
+		_ = evaluator.CheckContext(context.Background(), MapValues(vals), strict)
+	})
+	assert.Equal(t, 0.0, allocs)
+}
+
+func BenchmarkEvaluate(b *testing.B) {
+	r, _ := FromAuthCode(make([]byte, 32), MustMakeRestrictionsFromString("method^list|method^get|method=summary&method/listdatastore&time<1674742050"))
+	vals := map[string]any{"method": "summary", "time": 1674742049}
+
+	b.Run("interpreted", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			r.Evaluate(vals)
+		}
+	})
+
+	b.Run("compiled", func(b *testing.B) {
+		evaluator := r.Compile()
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			evaluator.Evaluate(vals)
+		}
+	})
+}
+
+func FuzzCompile(f *testing.F) {
+	f.Add("method^list|method^get&time<1674742050", "listpeers", "1674742049", int64(1), uint8(0xff))
+	f.Add("pnum>1.5|pnum=2&method{z&method}a", "m", "1e3", int64(-1), uint8(0x5))
+	f.Add("method!|time/3&pnum~1&method$s", "", "NaN", int64(math.MaxInt64), uint8(0x2))
+
+	f.Fuzz(func(t *testing.T, str, method, pnum string, time int64, present uint8) {
+		restrictions, err := MakeRestrictionsFromString(str)
+		if err != nil {
+			return
+		}
+		r, err := FromAuthCode(make([]byte, 32), restrictions)
+		if err != nil {
+			return
+		}
+
+		all := map[string]any{
+			"method": method,
+			"pnum":   pnum,
+			"time":   time,
+			"utime":  uint64(time) * 3,
+			"obtain": ObtainValue(func() any { return method }),
+			"ctx":    ObtainValueCtx(func(ctx context.Context) (any, bool, error) { return pnum, pnum != "", nil }),
+			"":       pnum,
+		}
+		vals := make(map[string]any)
+		i := 0
+		for _, field := range []string{"method", "pnum", "time", "utime", "obtain", "ctx", ""} {
+			if present&(1<<i) != 0 {
+				vals[field] = all[field]
+			}
+			i++
+		}
+
+		expectedOK, expectedMsg := r.Evaluate(vals)
+		ok, msg := r.Compile().Evaluate(vals)
+		if ok != expectedOK || msg != expectedMsg {
+			t.Fatalf("%q %v: expected %v %q got %v %q", str, vals, expectedOK, expectedMsg, ok, msg)
+		}
+
+		strict := EvalOptions{Strict: true}
+		expected := r.CheckContext(context.Background(), MapValues(vals), strict)
+		got := r.Compile().CheckContext(context.Background(), MapValues(vals), strict)
+		if !reflect.DeepEqual(expected, got) {
+			t.Fatalf("%q %v: expected %v got %v in strict mode", str, vals, expected, got)
+		}
+	})
+}
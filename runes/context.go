@@ -0,0 +1,95 @@
+package runes
+
+import (
+	"context"
+	"fmt"
+)
+
+// ObtainValueCtx is the signature of a function to get current value that can be absent (ok is false) or fail
+type ObtainValueCtx func(ctx context.Context) (value any, ok bool, err error)
+
+// ValueError is returned when a value could not be obtained
+type ValueError struct {
+	Field string
+	Err   error
+}
+
+// Error returns the error message
+func (e *ValueError) Error() string {
+	return fmt.Sprintf("could not obtain %s: %v", e.Field, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *ValueError) Unwrap() error {
+	return e.Err
+}
+
+// lookupFunc returns the value of field
+type lookupFunc func(field string) (value any, ok bool, err error)
+
+// contextLookup looks up and resolves every field at most once and stops when ctx is done
+func contextLookup(ctx context.Context, vals Values) lookupFunc {
+	type resolved struct {
+		value any
+		ok    bool
+		err   error
+	}
+	cache := make(map[string]resolved)
+
+	return func(field string) (any, bool, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, false, err
+		}
+
+		one, ok := cache[field]
+		if !ok {
+			one.value, one.ok, one.err = resolve(ctx, field, vals)
+			cache[field] = one
+		}
+
+		return one.value, one.ok, one.err
+	}
+}
+
+// resolve looks up field and calls ObtainValue or ObtainValueCtx
+func resolve(ctx context.Context, field string, vals Values) (any, bool, error) {
+	raw, ok := vals.Lookup(field)
+	if !ok {
+		return nil, false, nil
+	}
+
+	switch obtainer := raw.(type) {
+	case ObtainValueCtx:
+		value, ok, err := obtainer(ctx)
+		if err != nil {
+			return nil, false, &ValueError{Field: field, Err: err}
+		}
+		return value, ok, nil
+	case ObtainValue:
+		return obtainer(), true, nil
+	}
+
+	return raw, true, nil
+}
+
+// EvaluateContext evaluates the rune, only fields referenced by restrictions are looked up and each of them once.
+// ObtainValueCtx values are called with ctx, in strict mode type mismatches are errors instead of failing
+// restrictions. When a value cannot be obtained, ctx is done or types mismatch evaluation stops and the error
+// is returned (result has OutcomeError), denied restrictions are not errors and are reported by result alone.
+func (r *Rune) EvaluateContext(ctx context.Context, vals Values, opts EvalOptions) (*EvalResult, error) {
+	result := r.evaluateResult(contextLookup(ctx, vals), opts)
+	return result, result.Err
+}
+
+// CheckContext checks rune like EvaluateContext, it returns *EvalError when rune is denied
+func (r *Rune) CheckContext(ctx context.Context, vals Values, opts EvalOptions) error {
+	result, err := r.EvaluateContext(ctx, vals, opts)
+	if err != nil {
+		return err
+	}
+	if !result.OK() {
+		return &EvalError{Result: result}
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package runes
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type contextKey struct{}
+
+func TestEvaluateContext(t *testing.T) {
+	r, err := FromAuthCode(make([]byte, 32), MustMakeRestrictionsFromString("method^list|method^get|method=summary&method/listdatastore&pnum!|pnum<2"))
+	assert.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), contextKey{}, "summary")
+	calls := 0
+	vals := MapValues{
+		"method": ObtainValueCtx(func(ctx context.Context) (any, bool, error) {
+			calls++
+			return ctx.Value(contextKey{}), true, nil
+		}),
+		"pnum": ObtainValueCtx(func(ctx context.Context) (any, bool, error) {
+			return nil, false, nil
+		}),
+	}
+
+	result, err := r.EvaluateContext(ctx, vals, EvalOptions{})
+	assert.NoError(t, err)
+	assert.True(t, result.OK())
+	assert.Equal(t, 1, calls)
+	assert.NoError(t, r.CheckContext(ctx, vals, EvalOptions{}))
+
+	// Plain and ObtainValue values work too
+	err = r.CheckContext(context.Background(), MapValues{"method": ObtainValue(func() any { return "pay" }), "pnum": 1}, EvalOptions{})
+	var evalErr *EvalError
+	assert.True(t, errors.As(err, &evalErr))
+	assert.EqualError(t, err, "does not start with list AND does not start with get AND != summary")
+
+	assert.NoError(t, r.CheckContext(context.Background(), MapValues{"method": "listpeers", "pnum": 1}, EvalOptions{}))
+}
+
+func TestEvaluateContextError(t *testing.T) {
+	r, err := FromAuthCode(make([]byte, 32), MustMakeRestrictionsFromString("method^list|pnum=1&time<100"))
+	assert.NoError(t, err)
+
+	failure := errors.New("database down")
+	timeCalled := false
+	vals := MapValues{
+		"method": "pay",
+		"pnum": ObtainValueCtx(func(ctx context.Context) (any, bool, error) {
+			return nil, false, failure
+		}),
+		"time": ObtainValueCtx(func(ctx context.Context) (any, bool, error) {
+			timeCalled = true
+			return 1, true, nil
+		}),
+	}
+
+	result, err := r.EvaluateContext(context.Background(), vals, EvalOptions{})
+	assert.ErrorIs(t, err, failure)
+	var valueErr *ValueError
+	if assert.True(t, errors.As(err, &valueErr)) {
+		assert.Equal(t, "pnum", valueErr.Field)
+	}
+	assert.EqualError(t, err, "could not obtain pnum: database down")
+	assert.Equal(t, OutcomeError, result.Outcome)
+	assert.Equal(t, 0, result.Failed())
+	assert.Equal(t, OutcomeError, result.Restrictions[0].Alternatives[1].Outcome)
+	assert.Equal(t, OutcomeSkipped, result.Restrictions[1].Outcome)
+	assert.False(t, timeCalled)
+	assert.Contains(t, result.String(), "pnum=1: error (missing): could not obtain pnum: database down")
+
+	err = r.CheckContext(context.Background(), vals, EvalOptions{})
+	assert.ErrorIs(t, err, failure)
+	var evalErr *EvalError
+	assert.False(t, errors.As(err, &evalErr))
+}
+
+func TestEvaluateContextCancel(t *testing.T) {
+	r, err := FromAuthCode(make([]byte, 32), MustMakeRestrictionsFromString("method=pay&time<100"))
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	timeCalled := false
+	vals := MapValues{
+		"method": ObtainValueCtx(func(ctx context.Context) (any, bool, error) {
+			cancel()
+			return "pay", true, nil
+		}),
+		"time": ObtainValueCtx(func(ctx context.Context) (any, bool, error) {
+			timeCalled = true
+			return 1, true, nil
+		}),
+	}
+
+	result, err := r.EvaluateContext(ctx, vals, EvalOptions{})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, timeCalled)
+	assert.Equal(t, 1, result.Failed())
+	assert.Equal(t, OutcomePass, result.Restrictions[0].Outcome)
+
+	_, err = r.EvaluateContext(ctx, MapValues{"method": "pay", "time": 1}, EvalOptions{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestObtainValueCtxEverywhere(t *testing.T) {
+	master := MustMakeMasterRune([]byte("secret"))
+	r := master.MustGetRestrictedFromString("method/pay")
+	verifier, err := NewVerifier(&master, 10)
+	assert.NoError(t, err)
+	keyring := NewKeyring()
+	assert.NoError(t, keyring.AddKey(1, []byte("secret")))
+	issued, err := keyring.Issue(1, MustMakeRestrictionsFromString("method/pay"))
+	assert.NoError(t, err)
+
+	pay := ObtainValueCtx(func(ctx context.Context) (any, bool, error) { return "pay", true, nil })
+	vals := map[string]any{"method": pay}
+
+	ok, msg := r.Evaluate(vals)
+	assert.False(t, ok)
+	assert.Equal(t, "= pay", msg)
+	ok, msg = r.Compile().Evaluate(vals)
+	assert.False(t, ok)
+	assert.Equal(t, "= pay", msg)
+	ok, _ = r.Restrictions[0].Evaluate(vals)
+	assert.False(t, ok)
+	ok, _ = r.Restrictions[0].Alternatives[0].Evaluate(vals)
+	assert.False(t, ok)
+
+	for _, err := range []error{
+		r.Check(vals),
+		r.Compile().Check(vals),
+		master.Check(&r, vals),
+		master.CheckContext(context.Background(), &r, MapValues(vals), EvalOptions{}),
+		verifier.Check(&r, vals),
+		verifier.CheckContext(context.Background(), &r, MapValues(vals), EvalOptions{}),
+		keyring.Check(issued, vals),
+		keyring.CheckContext(context.Background(), issued, MapValues(vals), EvalOptions{}),
+	} {
+		assert.EqualError(t, err, "= pay")
+	}
+
+	// Errors are not silently ignored either
+	failing := map[string]any{"method": ObtainValueCtx(func(ctx context.Context) (any, bool, error) { return nil, false, errors.New("down") })}
+	ok, msg = r.Compile().Evaluate(failing)
+	assert.False(t, ok)
+	assert.Equal(t, "could not obtain method: down", msg)
+	ok, msg = r.Evaluate(failing)
+	assert.False(t, ok)
+	assert.Equal(t, "could not obtain method: down", msg)
+	assert.Error(t, master.Check(&r, failing))
+
+	assert.NoError(t, master.CheckContext(context.Background(), &r, MapValues{"method": "getinfo"}, EvalOptions{}))
+	assert.NoError(t, keyring.CheckContext(context.Background(), issued, MapValues{"method": "getinfo"}, EvalOptions{}))
+}
@@ -0,0 +1,74 @@
+package runes
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+)
+
+const (
+	// DerivedSecretSize is the size of a derived master secret in bytes
+	DerivedSecretSize = 32
+
+	deriveSalt = "go-runes derive master"
+	deriveInfo = "go-runes master v1\x00"
+)
+
+// hkdfExtract is HKDF-Extract from RFC 5869 using HMAC-SHA256
+func hkdfExtract(salt, ikm []byte) []byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(ikm)
+	return mac.Sum(nil)
+}
+
+// hkdfExpand is HKDF-Expand from RFC 5869 using HMAC-SHA256
+func hkdfExpand(prk, info []byte, length int) ([]byte, error) {
+	if length > 255*sha256.Size {
+		return nil, fmt.Errorf("hkdf output too long")
+	}
+
+	ret := make([]byte, 0, length+sha256.Size)
+	var prev []byte
+	for counter := byte(1); len(ret) < length; counter++ {
+		mac := hmac.New(sha256.New, prk)
+		mac.Write(prev)
+		mac.Write(info)
+		mac.Write([]byte{counter})
+		prev = mac.Sum(nil)
+		ret = append(ret, prev...)
+	}
+
+	return ret[:length], nil
+}
+
+// DeriveSecret derives a stable master secret for label from root key using HKDF-SHA256
+func DeriveSecret(root []byte, label string) ([]byte, error) {
+	if len(root) < 1 {
+		return nil, ErrTooShortSecret
+	}
+
+	// Label is the last part of info so different labels can never produce the same info
+	prk := hkdfExtract([]byte(deriveSalt), root)
+	return hkdfExpand(prk, []byte(deriveInfo+label), DerivedSecretSize)
+}
+
+// DeriveMasterRune creates a new master rune with a secret derived for label from root key.
+// Since every label has its own secret, unique ids of different labels live in separate namespaces
+// (a rune with unique id 1 issued for one label is never authorized by a master of another label).
+func DeriveMasterRune(root []byte, label string, uniqueid, version any, restrictions []Restriction) (*MasterRune, error) {
+	secret, err := DeriveSecret(root, label)
+	if err != nil {
+		return nil, err
+	}
+
+	return MakeMasterRune(secret, uniqueid, version, restrictions)
+}
+
+// MustDeriveMasterRune is a helper constructor for deriving a master rune for label
+func MustDeriveMasterRune(root []byte, label string) MasterRune {
+	rune, err := DeriveMasterRune(root, label, nil, nil, nil)
+	if err != nil {
+		panic(err)
+	}
+	return *rune
+}
@@ -0,0 +1,67 @@
+package runes
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHkdfVector(t *testing.T) {
+	// RFC 5869 test case 1
+	ikm, _ := hex.DecodeString("0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b")
+	salt, _ := hex.DecodeString("000102030405060708090a0b0c")
+	info, _ := hex.DecodeString("f0f1f2f3f4f5f6f7f8f9")
+
+	prk := hkdfExtract(salt, ikm)
+	assert.Equal(t, "077709362c2e32df0ddc3f0dc47bba6390b6c73bb50f9c3122ec844ad7c2b3e5", hex.EncodeToString(prk))
+
+	okm, err := hkdfExpand(prk, info, 42)
+	assert.NoError(t, err)
+	assert.Equal(t, "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865", hex.EncodeToString(okm))
+}
+
+func TestDeriveSecret(t *testing.T) {
+	root := []byte("root key")
+
+	a, err := DeriveSecret(root, "tenant")
+	assert.NoError(t, err)
+	assert.Equal(t, DerivedSecretSize, len(a))
+
+	// Stable
+	b, err := DeriveSecret(root, "tenant")
+	assert.NoError(t, err)
+	assert.Equal(t, a, b)
+
+	seen := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		label := fmt.Sprintf("tenant%d", i)
+		secret, err := DeriveSecret(root, label)
+		assert.NoError(t, err)
+		other, ok := seen[string(secret)]
+		assert.False(t, ok, "%s collides with %s", label, other)
+		seen[string(secret)] = label
+	}
+
+	_, err = DeriveSecret(nil, "tenant")
+	assert.ErrorIs(t, err, ErrTooShortSecret)
+}
+
+func TestDeriveMasterRune(t *testing.T) {
+	root := []byte("root key")
+
+	one, err := DeriveMasterRune(root, "one", 1, nil, nil)
+	assert.NoError(t, err)
+	two, err := DeriveMasterRune(root, "two", 1, nil, nil)
+	assert.NoError(t, err)
+
+	// Same unique id under different labels does not cross authorize
+	restricted := one.MustGetRestrictedFromString("method^list")
+	assert.Equal(t, true, one.IsRuneAuthorized(&restricted))
+	assert.Equal(t, false, two.IsRuneAuthorized(&restricted))
+	assert.Equal(t, 1, restricted.GetUniqueID())
+
+	again := MustDeriveMasterRune(root, "one")
+	assert.Equal(t, one.SeedSecret, again.SeedSecret)
+}
@@ -0,0 +1,65 @@
+package runes
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Describe returns a human readable description of the alternative (wording follows CoreLightning showrune)
+func (a *Alternative) Describe() string {
+	if a.IsUniqueID() {
+		id := fmt.Sprintf("%v", a.Value)
+		split := strings.SplitN(id, "-", 2)
+		if len(split) > 1 {
+			return fmt.Sprintf("unique id is %s version %s", split[0], split[1])
+		}
+		return fmt.Sprintf("unique id is %s", id)
+	}
+
+	switch a.Cond {
+	case "!":
+		return fmt.Sprintf("%s is missing", a.Field)
+	case "=":
+		return fmt.Sprintf("%s equal to %v", a.Field, a.Value)
+	case "/":
+		return fmt.Sprintf("%s unequal to %v", a.Field, a.Value)
+	case "^":
+		return fmt.Sprintf("%s starts with %v", a.Field, a.Value)
+	case "$":
+		return fmt.Sprintf("%s ends with %v", a.Field, a.Value)
+	case "~":
+		return fmt.Sprintf("%s contains %v", a.Field, a.Value)
+	case "<":
+		return fmt.Sprintf("%s is less than %v", a.Field, a.Value)
+	case ">":
+		return fmt.Sprintf("%s is greater than %v", a.Field, a.Value)
+	case "{":
+		return fmt.Sprintf("%s sorts before %v", a.Field, a.Value)
+	case "}":
+		return fmt.Sprintf("%s sorts after %v", a.Field, a.Value)
+	case "#":
+		return fmt.Sprintf("comment: %s %v", a.Field, a.Value)
+	default:
+		return fmt.Sprintf("%s %s %v", a.Field, a.Cond, a.Value)
+	}
+}
+
+// Describe returns a human readable description of the restriction
+func (r *Restriction) Describe() string {
+	str := make([]string, 0, len(r.Alternatives))
+	for _, one := range r.Alternatives {
+		str = append(str, one.Describe())
+	}
+
+	return strings.Join(str, " OR ")
+}
+
+// Describe returns a human readable description of the rune
+func (r *Rune) Describe() string {
+	str := make([]string, 0, len(r.Restrictions))
+	for _, one := range r.Restrictions {
+		str = append(str, one.Describe())
+	}
+
+	return strings.Join(str, " AND ")
+}
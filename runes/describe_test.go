@@ -0,0 +1,45 @@
+package runes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribeRune(t *testing.T) {
+	var secret [16]byte
+	master, err := MakeMasterRune(secret[:], 3, 1, nil)
+	assert.NoError(t, err)
+
+	rune := master.MustGetRestrictedFromString("method^list|method^get&time<1700000000")
+	assert.Equal(t, "unique id is 3 version 1 AND method starts with list OR method starts with get AND time is less than 1700000000", rune.Describe())
+
+	plain := MustMakeMasterRune(secret[:])
+	rune = plain.MustGetRestrictedFromString("=5&method=getinfo")
+	assert.Equal(t, "unique id is 5 AND method equal to getinfo", rune.Describe())
+	assert.Equal(t, "", plain.Describe())
+}
+
+func TestDescribeConditions(t *testing.T) {
+	expected := map[string]string{
+		"!": "f is missing",
+		"=": "f equal to v",
+		"/": "f unequal to v",
+		"^": "f starts with v",
+		"$": "f ends with v",
+		"~": "f contains v",
+		"<": "f is less than v",
+		">": "f is greater than v",
+		"{": "f sorts before v",
+		"}": "f sorts after v",
+		"#": "comment: f v",
+	}
+
+	for _, cond := range KnownConditions {
+		alt, err := MakeAlternative("f", cond, "v", false)
+		assert.NoError(t, err)
+
+		restriction := Restriction{Alternatives: []Alternative{*alt}}
+		assert.Equal(t, expected[cond], restriction.Describe(), cond)
+	}
+}
@@ -0,0 +1,184 @@
+package runes
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Outcome of evaluating a rune, restriction or alternative
+type Outcome int
+
+const (
+	// OutcomePass means evaluation succeeded
+	OutcomePass Outcome = iota
+	// OutcomeFail means evaluation failed
+	OutcomeFail
+	// OutcomeSkipped means restriction was not evaluated since an earlier one already failed
+	OutcomeSkipped
+	// OutcomeError means a value could not be obtained, so nothing was decided
+	OutcomeError
+)
+
+// RedactedValue replaces actual values in redacted results
+const RedactedValue = "[redacted]"
+
+// String returns the name of outcome
+func (o Outcome) String() string {
+	switch o {
+	case OutcomePass:
+		return "pass"
+	case OutcomeFail:
+		return "fail"
+	case OutcomeSkipped:
+		return "skipped"
+	case OutcomeError:
+		return "error"
+	default:
+		return fmt.Sprintf("outcome(%d)", int(o))
+	}
+}
+
+// MarshalText encodes outcome as its name
+func (o Outcome) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+// UnmarshalText decodes outcome from its name
+func (o *Outcome) UnmarshalText(data []byte) error {
+	for _, one := range []Outcome{OutcomePass, OutcomeFail, OutcomeSkipped, OutcomeError} {
+		if one.String() == string(data) {
+			*o = one
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown outcome %s", string(data))
+}
+
+// AlternativeResult is the result of evaluating one alternative
+type AlternativeResult struct {
+	Field    string `json:"field"`
+	Operator string `json:"operator"`
+	Expected string `json:"expected"`
+	// Actual is nil when the field is missing
+	Actual  *string `json:"actual,omitempty"`
+	Outcome Outcome `json:"outcome"`
+	Reason  string  `json:"reason,omitempty"`
+
+	err error
+}
+
+// String returns a human readable representation of result
+func (r AlternativeResult) String() string {
+	actual := "missing"
+	if r.Actual != nil {
+		actual = fmt.Sprintf("actual %q", *r.Actual)
+	}
+
+	ret := fmt.Sprintf("%s%s%s: %s (%s)", r.Field, r.Operator, escape(r.Expected), r.Outcome, actual)
+	if r.Reason != "" {
+		ret += ": " + r.Reason
+	}
+	return ret
+}
+
+// RestrictionResult is the result of evaluating one restriction, alternatives after the first passing one are not evaluated
+type RestrictionResult struct {
+	Restriction  string              `json:"restriction"`
+	Outcome      Outcome             `json:"outcome"`
+	Alternatives []AlternativeResult `json:"alternatives,omitempty"`
+
+	err error
+}
+
+// Reason returns why restriction failed (empty when it did not)
+func (r RestrictionResult) Reason() string {
+	if r.Outcome != OutcomeFail && r.Outcome != OutcomeError {
+		return ""
+	}
+
+	reasons := make([]string, 0, len(r.Alternatives))
+	for _, alt := range r.Alternatives {
+		reasons = append(reasons, alt.Reason)
+	}
+	return strings.Join(reasons, " AND ")
+}
+
+// EvalResult is the result of evaluating a rune
+type EvalResult struct {
+	Outcome      Outcome             `json:"outcome"`
+	Restrictions []RestrictionResult `json:"restrictions"`
+	// Err is set when outcome is OutcomeError
+	Err error `json:"-"`
+}
+
+// OK reports whether evaluation succeeded
+func (r *EvalResult) OK() bool {
+	return r.Outcome == OutcomePass
+}
+
+// Failed returns the index of the failed (or erroneous) restriction or -1
+func (r *EvalResult) Failed() int {
+	for i, restriction := range r.Restrictions {
+		if restriction.Outcome == OutcomeFail || restriction.Outcome == OutcomeError {
+			return i
+		}
+	}
+	return -1
+}
+
+// Reason returns why evaluation failed (empty when it did not)
+func (r *EvalResult) Reason() string {
+	i := r.Failed()
+	if i < 0 {
+		return ""
+	}
+	return r.Restrictions[i].Reason()
+}
+
+// Redact returns a copy of result where actual values are replaced with RedactedValue
+func (r *EvalResult) Redact() *EvalResult {
+	ret := &EvalResult{Outcome: r.Outcome, Err: r.Err, Restrictions: make([]RestrictionResult, 0, len(r.Restrictions))}
+	redacted := RedactedValue
+
+	for _, restriction := range r.Restrictions {
+		one := restriction
+		if restriction.Alternatives != nil {
+			one.Alternatives = make([]AlternativeResult, 0, len(restriction.Alternatives))
+			for _, alt := range restriction.Alternatives {
+				if alt.Actual != nil {
+					alt.Actual = &redacted
+				}
+				one.Alternatives = append(one.Alternatives, alt)
+			}
+		}
+		ret.Restrictions = append(ret.Restrictions, one)
+	}
+
+	return ret
+}
+
+// String returns a human readable trace of evaluation
+func (r *EvalResult) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(r.Outcome.String())
+	for i, restriction := range r.Restrictions {
+		fmt.Fprintf(&sb, "\nrestriction %d %s: %s", i, restriction.Restriction, restriction.Outcome)
+		for _, alt := range restriction.Alternatives {
+			sb.WriteString("\n  ")
+			sb.WriteString(alt.String())
+		}
+	}
+
+	return sb.String()
+}
+
+// EvalError is returned when rune evaluation fails
+type EvalError struct {
+	Result *EvalResult
+}
+
+// Error returns the reason of failure
+func (e *EvalError) Error() string {
+	return e.Result.Reason()
+}
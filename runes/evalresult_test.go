@@ -0,0 +1,118 @@
+package runes
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateResult(t *testing.T) {
+	r, err := FromAuthCode(make([]byte, 32), MustMakeRestrictionsFromString("method^list|method^get&pnum<2&time>100"))
+	assert.NoError(t, err)
+
+	result := r.EvaluateResult(map[string]any{"method": "getinfo", "pnum": 3, "time": 200})
+	assert.False(t, result.OK())
+	assert.Equal(t, 1, result.Failed())
+	assert.Equal(t, ">= 2", result.Reason())
+
+	getinfo, three := "getinfo", "3"
+	assert.Equal(t, &EvalResult{
+		Outcome: OutcomeFail,
+		Restrictions: []RestrictionResult{
+			{Restriction: "method^list|method^get", Outcome: OutcomePass, Alternatives: []AlternativeResult{
+				{Field: "method", Operator: "^", Expected: "list", Actual: &getinfo, Outcome: OutcomeFail, Reason: "does not start with list"},
+				{Field: "method", Operator: "^", Expected: "get", Actual: &getinfo, Outcome: OutcomePass},
+			}},
+			{Restriction: "pnum<2", Outcome: OutcomeFail, Alternatives: []AlternativeResult{
+				{Field: "pnum", Operator: "<", Expected: "2", Actual: &three, Outcome: OutcomeFail, Reason: ">= 2"},
+			}},
+			{Restriction: "time>100", Outcome: OutcomeSkipped},
+		},
+	}, result)
+
+	assert.Equal(t, `fail
+restriction 0 method^list|method^get: pass
+  method^list: fail (actual "getinfo"): does not start with list
+  method^get: pass (actual "getinfo")
+restriction 1 pnum<2: fail
+  pnum<2: fail (actual "3"): >= 2
+restriction 2 time>100: skipped`, result.String())
+
+	result = r.EvaluateResult(map[string]any{"method": "listpeers", "pnum": 1, "time": 200})
+	assert.True(t, result.OK())
+	assert.Equal(t, -1, result.Failed())
+	assert.Equal(t, "", result.Reason())
+
+	result = r.EvaluateResult(map[string]any{"pnum": 1})
+	assert.Equal(t, "method is missing AND method is missing", result.Reason())
+	assert.Nil(t, result.Restrictions[0].Alternatives[0].Actual)
+	assert.Contains(t, result.String(), "method^list: fail (missing): method is missing")
+}
+
+func TestEvaluateResultJSON(t *testing.T) {
+	r, err := FromAuthCode(make([]byte, 32), MustMakeRestrictionsFromString("method=pay&pnum!"))
+	assert.NoError(t, err)
+
+	result := r.EvaluateResult(map[string]any{"method": "getinfo"})
+	data, err := json.Marshal(result)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"outcome":"fail","restrictions":[
+		{"restriction":"method=pay","outcome":"fail","alternatives":[{"field":"method","operator":"=","expected":"pay","actual":"getinfo","outcome":"fail","reason":"!= pay"}]},
+		{"restriction":"pnum!","outcome":"skipped"}]}`, string(data))
+
+	var decoded EvalResult
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, result, &decoded)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"outcome":"maybe"}`), &decoded))
+}
+
+func TestEvaluateResultRedact(t *testing.T) {
+	r, err := FromAuthCode(make([]byte, 32), MustMakeRestrictionsFromString("secret=foo|other!"))
+	assert.NoError(t, err)
+
+	result := r.EvaluateResult(map[string]any{"secret": "hunter2", "other": 1})
+	redacted := result.Redact()
+	assert.Equal(t, "hunter2", *result.Restrictions[0].Alternatives[0].Actual)
+	assert.Equal(t, RedactedValue, *redacted.Restrictions[0].Alternatives[0].Actual)
+	assert.Equal(t, RedactedValue, *redacted.Restrictions[0].Alternatives[1].Actual)
+	assert.NotContains(t, redacted.String(), "hunter2")
+	assert.Equal(t, result.Reason(), redacted.Reason())
+
+	data, err := json.Marshal(redacted)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "hunter2")
+
+	// Lexicographic and strict mode failures do not mention the actual value either
+	r, err = FromAuthCode(make([]byte, 32), MustMakeRestrictionsFromString("secret{a|secret}z|secret<5"))
+	assert.NoError(t, err)
+	result = r.EvaluateResult(map[string]any{"secret": "hunter2"})
+	assert.Equal(t, 0, result.Failed())
+	assert.NotContains(t, result.Redact().String(), "hunter2")
+	assert.Equal(t, "is the same or ordered after a AND is the same or ordered before z AND >= 5", result.Reason())
+	ok, msg := r.Compile().Evaluate(map[string]any{"secret": "hunter2"})
+	assert.False(t, ok)
+	assert.Equal(t, result.Reason(), msg)
+
+	result, err = r.EvaluateWithOptions(map[string]any{"secret": "hunter2"}, EvalOptions{Strict: true})
+	assert.Error(t, err)
+	assert.NotContains(t, result.Redact().String(), "hunter2")
+}
+
+func TestCheckEvalError(t *testing.T) {
+	master := MustMakeMasterRune([]byte("secret"))
+	r := master.MustGetRestrictedFromString("method=pay")
+
+	for _, err := range []error{r.Check(map[string]any{"method": "getinfo"}), master.Check(&r, map[string]any{"method": "getinfo"})} {
+		assert.EqualError(t, err, "!= pay")
+
+		var evalErr *EvalError
+		if assert.True(t, errors.As(err, &evalErr)) {
+			assert.Equal(t, 0, evalErr.Result.Failed())
+		}
+	}
+
+	assert.NoError(t, r.Check(map[string]any{"method": "pay"}))
+}
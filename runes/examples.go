@@ -0,0 +1,42 @@
+package runes
+
+// Example holds values demonstrating a single restriction of a rune
+type Example struct {
+	// Restriction is the index of the restriction in the rune
+	Restriction int
+	// Pass are values that pass every restriction
+	Pass map[string]any
+	// Fail are values that fail only this restriction (nil when no such values exist, e.g. for comments)
+	Fail map[string]any
+}
+
+// Examples returns passing and failing values for each restriction of the rune
+func Examples(r *Rune) ([]Example, error) {
+	pass, err := r.Satisfiable()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]Example, 0, len(r.Restrictions))
+	for i := range r.Restrictions {
+		others := make([]Restriction, 0, len(r.Restrictions)-1)
+		others = append(others, r.Restrictions[:i]...)
+		others = append(others, r.Restrictions[i+1:]...)
+
+		example := Example{Restriction: i, Pass: copyValues(pass)}
+		if fail, ok := findWitness(others, &r.Restrictions[i]); ok {
+			example.Fail = fail
+		}
+		ret = append(ret, example)
+	}
+
+	return ret, nil
+}
+
+func copyValues(vals map[string]any) map[string]any {
+	ret := make(map[string]any, len(vals))
+	for k, v := range vals {
+		ret[k] = v
+	}
+	return ret
+}
@@ -0,0 +1,50 @@
+package runes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExamples(t *testing.T) {
+	master := MustMakeMasterRune([]byte("secret"))
+	id, err := UniqueID("1", "2")
+	assert.NoError(t, err)
+
+	restrictions := append([]Restriction{*id}, MustMakeRestrictionsFromString("method^list|method=pay&time<100&pnum!|pnum=1&note#doc")...)
+	r, err := master.GetRestricted(restrictions...)
+	assert.NoError(t, err)
+
+	examples, err := Examples(r)
+	assert.NoError(t, err)
+	assert.Equal(t, len(r.Restrictions), len(examples))
+
+	for i, example := range examples {
+		assert.Equal(t, i, example.Restriction)
+
+		for j, restriction := range r.Restrictions {
+			ok, _ := restriction.Evaluate(example.Pass)
+			assert.True(t, ok, "%d %d", i, j)
+		}
+
+		if r.Restrictions[i].Alternatives[0].Cond == "#" {
+			assert.Nil(t, example.Fail)
+			continue
+		}
+
+		if assert.NotNil(t, example.Fail, "%d", i) {
+			for j, restriction := range r.Restrictions {
+				ok, _ := restriction.Evaluate(example.Fail)
+				assert.Equal(t, i != j, ok, "%d %d", i, j)
+			}
+		}
+	}
+}
+
+func TestExamplesUnsatisfiable(t *testing.T) {
+	r, err := FromAuthCode(make([]byte, 32), MustMakeRestrictionsFromString("time<10&time>20"))
+	assert.NoError(t, err)
+
+	_, err = Examples(r)
+	assert.ErrorIs(t, err, ErrUnsatisfiable)
+}
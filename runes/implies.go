@@ -0,0 +1,205 @@
+package runes
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Implies reports whether restrictions a grant no more than restrictions b, i.e. every set of values
+// accepted by a is also accepted by b. The check is sound but not complete: true is only returned when the
+// implication was proven, false means it is not implied or it could not be proven.
+// An error is returned for restrictions with unknown conditions.
+func Implies(a, b []Restriction) (bool, error) {
+	for _, restrictions := range [][]Restriction{a, b} {
+		for _, r := range restrictions {
+			for _, alt := range r.Alternatives {
+				if !knownCondition(alt.Cond) {
+					return false, fmt.Errorf("cond not valid %s", alt.Cond)
+				}
+			}
+		}
+	}
+
+	for _, rb := range b {
+		if isTautology(rb) {
+			continue
+		}
+
+		implied := false
+		for _, ra := range a {
+			if restrictionImplies(ra, rb) {
+				implied = true
+				break
+			}
+		}
+		if !implied {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// isTautology reports whether restriction is always satisfied
+func isTautology(r Restriction) bool {
+	missing := make(map[string]bool)
+	present := make(map[string]bool)
+
+	for _, alt := range r.Alternatives {
+		if alt.IsUniqueID() {
+			continue
+		}
+		if alt.Cond == "#" {
+			return true
+		}
+		if alt.Cond == "!" {
+			missing[alt.Field] = true
+		}
+		if requiresOnlyPresence(alt) {
+			present[alt.Field] = true
+		}
+	}
+
+	for field := range missing {
+		if present[field] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// requiresOnlyPresence reports whether alternative is satisfied by any present value
+func requiresOnlyPresence(alt Alternative) bool {
+	switch alt.Cond {
+	case "^", "$", "~":
+		return fmt.Sprintf("%v", alt.Value) == ""
+	}
+
+	return false
+}
+
+// restrictionImplies reports whether every alternative of a implies some alternative of b
+func restrictionImplies(a, b Restriction) bool {
+	if len(a.Alternatives) == 0 {
+		return false
+	}
+
+	for _, altA := range a.Alternatives {
+		implied := false
+		for _, altB := range b.Alternatives {
+			if alternativeImplies(altA, altB) {
+				implied = true
+				break
+			}
+		}
+		if !implied {
+			return false
+		}
+	}
+
+	return true
+}
+
+// alternativeImplies reports whether a being satisfied guarantees b is satisfied.
+// Conditions other than < and > only depend on the string form of the actual value (see coerceString), numeric
+// comparisons are exact so the literals alone decide.
+func alternativeImplies(a, b Alternative) bool {
+	va := fmt.Sprintf("%v", a.Value)
+	vb := fmt.Sprintf("%v", b.Value)
+
+	if a.Field == b.Field && a.Cond == b.Cond && va == vb {
+		return true
+	}
+	if a.IsUniqueID() || b.IsUniqueID() {
+		return false
+	}
+	if b.Cond == "#" {
+		return true
+	}
+	if a.Field != b.Field || a.Cond == "#" || a.Cond == "!" || b.Cond == "!" {
+		return false
+	}
+
+	// From here on a requires field to be present
+	if requiresOnlyPresence(b) {
+		return true
+	}
+
+	switch a.Cond {
+	case "=":
+		// Value is pinned exactly
+		ok, _ := b.Evaluate(map[string]any{b.Field: va})
+		return ok
+	}
+
+	if b.Cond == "/" {
+		// Value vb can never satisfy a
+		ok, _ := a.Evaluate(map[string]any{a.Field: vb})
+		return !ok
+	}
+
+	switch a.Cond {
+	case "^":
+		switch b.Cond {
+		case "^":
+			return strings.HasPrefix(va, vb)
+		case "~":
+			return strings.Contains(va, vb)
+		case "{":
+			return !strings.HasPrefix(vb, va) && va < vb
+		case "}":
+			return va > vb
+		}
+	case "$":
+		switch b.Cond {
+		case "$":
+			return strings.HasSuffix(va, vb)
+		case "~":
+			return strings.Contains(va, vb)
+		}
+	case "~":
+		if b.Cond == "~" {
+			return strings.Contains(va, vb)
+		}
+	case "{":
+		if b.Cond == "{" {
+			return va <= vb
+		}
+	case "}":
+		if b.Cond == "}" {
+			return va >= vb
+		}
+	case "<":
+		if b.Cond == "<" {
+			return numericImplies(va, vb, false)
+		}
+	case ">":
+		if b.Cond == ">" {
+			return numericImplies(va, vb, true)
+		}
+	}
+
+	return false
+}
+
+// numericImplies reports whether x < a implies x < b (or x > a implies x > b when greater is set)
+func numericImplies(a, b string, greater bool) bool {
+	numA, ok := parseNumber(a)
+	if !ok {
+		return false
+	}
+	numB, ok := parseNumber(b)
+	if !ok {
+		return false
+	}
+
+	cmp, ok := compareNumbers(numA, numB)
+	if !ok {
+		return false
+	}
+	if greater {
+		return cmp >= 0
+	}
+	return cmp <= 0
+}
@@ -0,0 +1,137 @@
+package runes
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImplies(t *testing.T) {
+	for _, c := range []struct {
+		a, b     string
+		expected bool
+	}{
+		{"method=listpeers", "method^list", true},
+		{"method^list", "method=listpeers", false},
+		{"method^listpeers", "method^list", true},
+		{"method^list", "method^listpeers", false},
+		{"method^list", "method~ist", true},
+		{"method$peers", "method~peer", true},
+		{"method~peers", "method~eer", true},
+		{"method=pay", "method/getinfo", true},
+		{"method^list", "method/getinfo", true},
+		{"method^list", "method/listpeers", false},
+		{"method^list", "method{m", true},
+		{"method^list", "method{listz", false},
+		{"method^list", "method}lisa", true},
+		{"method^list", "method}list", false},
+		{"method{b", "method{c", true},
+		{"method}c", "method}b", true},
+		{"time<100", "time<200", true},
+		{"time<200", "time<100", false},
+		{"time>200", "time>100", true},
+		{"time<100", "time<100.5", true},
+		{"time<100.5", "time<101", true},
+		{"time<100.5", "time<100", false},
+		{"time<18446744073709551616", "time<18446744073709551617", true},
+		{"time<18446744073709551617", "time<18446744073709551616", false},
+		{"time>1e3", "time>999", true},
+		{"time<NaN", "time<1", false},
+		{"time<100", "time/150", true},
+		{"time<100", "time/50", false},
+		{"time=50", "time<100", true},
+		{"time=150", "time<100", false},
+		{"method!", "method!", true},
+		{"method!", "method/pay", false},
+		{"method=pay", "method!", false},
+		{"method=pay", "method~", true},
+		{"method=pay", "other#comment", true},
+		{"method=pay", "method!|method~", true},
+		{"method=pay", "other=1", false},
+		{"method=pay|method=get", "method=pay|method=get|method=list", true},
+		{"method=pay|method=get", "method=pay", false},
+		{"method=pay&time<100", "time<200", true},
+		{"method=pay&time<100", "method=pay&time<200&id=1", false},
+		{"=1&method=pay", "=1", true},
+		{"=1&method=pay", "=2", false},
+		{"method=pay", "", true},
+		{"", "method=pay", false},
+	} {
+		var a, b []Restriction
+		if c.a != "" {
+			a = MustMakeRestrictionsFromString(c.a)
+		}
+		if c.b != "" {
+			b = MustMakeRestrictionsFromString(c.b)
+		}
+
+		ok, err := Implies(a, b)
+		assert.NoError(t, err)
+		assert.Equal(t, c.expected, ok, "%s => %s", c.a, c.b)
+	}
+
+	_, err := Implies([]Restriction{{Alternatives: []Alternative{{Field: "a", Cond: "@", Value: "1"}}}}, nil)
+	assert.Error(t, err)
+}
+
+func TestImpliesSound(t *testing.T) {
+	conds := KnownConditions
+	values := []string{"", "1", "10", "100", "1.5", "-3", "a", "ab", "b", "list", "listpeers", "peers"}
+	fields := []string{"f", "g"}
+
+	rnd := rand.New(rand.NewSource(1))
+	randomRestrictions := func() []Restriction {
+		ret := make([]Restriction, 0)
+		for i := 0; i < 1+rnd.Intn(2); i++ {
+			r := Restriction{}
+			for j := 0; j < 1+rnd.Intn(2); j++ {
+				r.Alternatives = append(r.Alternatives, Alternative{
+					Field: fields[rnd.Intn(len(fields))],
+					Cond:  conds[rnd.Intn(len(conds))],
+					Value: values[rnd.Intn(len(values))],
+				})
+			}
+			ret = append(ret, r)
+		}
+		return ret
+	}
+
+	valueMaps := make([]map[string]any, 0)
+	for _, f := range append([]string{"missing"}, values...) {
+		for _, g := range append([]string{"missing"}, values...) {
+			vals := make(map[string]any)
+			if f != "missing" {
+				vals["f"] = f
+			}
+			if g != "missing" {
+				vals["g"] = g
+			}
+			valueMaps = append(valueMaps, vals)
+		}
+	}
+	for _, extra := range []string{"0", "99", "100.5", "listp", "lisa", "z", "aa"} {
+		valueMaps = append(valueMaps, map[string]any{"f": extra, "g": extra})
+	}
+
+	proven := 0
+	for i := 0; i < 20000; i++ {
+		a := randomRestrictions()
+		b := randomRestrictions()
+
+		ok, err := Implies(a, b)
+		assert.NoError(t, err)
+		if !ok {
+			continue
+		}
+		proven++
+
+		for _, vals := range valueMaps {
+			if evaluateRestrictions(a, vals) && !evaluateRestrictions(b, vals) {
+				t.Fatalf("unsound: %v => %v fails for %v", a, b, vals)
+			}
+		}
+	}
+
+	assert.Greater(t, proven, 100)
+}
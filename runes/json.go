@@ -0,0 +1,194 @@
+package runes
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// JSON representation:
+//
+//	{
+//	  "authcode": "<64 hex digits>",
+//	  "unique_id": "3",
+//	  "version": "1",
+//	  "restrictions": [[{"field": "method", "cond": "^", "value": "list"}, ...], ...]
+//	}
+//
+// Values are always encoded as strings (the same way they are hashed), unique_id and version are informational
+// (they are also part of the first restriction). CompactRune encodes a rune as just its base64 string.
+
+type alternativeJSON struct {
+	Field string `json:"field"`
+	Cond  string `json:"cond"`
+	Value string `json:"value"`
+}
+
+type runeJSON struct {
+	AuthCode     string          `json:"authcode"`
+	UniqueID     string          `json:"unique_id,omitempty"`
+	Version      string          `json:"version,omitempty"`
+	Restrictions [][]Alternative `json:"restrictions"`
+}
+
+// CompactRune is a rune that is encoded as base64 string in JSON
+type CompactRune Rune
+
+// MarshalJSON encodes the alternative as JSON
+func (a Alternative) MarshalJSON() ([]byte, error) {
+	return json.Marshal(alternativeJSON{Field: a.Field, Cond: a.Cond, Value: fmt.Sprintf("%v", a.Value)})
+}
+
+// UnmarshalJSON decodes the alternative from JSON (unique id field is allowed)
+func (a *Alternative) UnmarshalJSON(data []byte) error {
+	var tmp alternativeJSON
+	err := json.Unmarshal(data, &tmp)
+	if err != nil {
+		return err
+	}
+
+	alt, err := MakeAlternative(tmp.Field, tmp.Cond, tmp.Value, true)
+	if err != nil {
+		return err
+	}
+	*a = *alt
+
+	return nil
+}
+
+// MarshalJSON encodes the restriction as JSON array of alternatives
+func (r Restriction) MarshalJSON() ([]byte, error) {
+	if r.Alternatives == nil {
+		return []byte("[]"), nil
+	}
+
+	return json.Marshal(r.Alternatives)
+}
+
+// UnmarshalJSON decodes the restriction from JSON array of alternatives
+func (r *Restriction) UnmarshalJSON(data []byte) error {
+	var alternatives []Alternative
+	err := json.Unmarshal(data, &alternatives)
+	if err != nil {
+		return err
+	}
+
+	ret, err := checkRestriction(alternatives, true)
+	if err != nil {
+		return err
+	}
+	*r = *ret
+
+	return nil
+}
+
+// MarshalJSON encodes the rune in structured JSON form, a rune without auth code (zero value) cannot be encoded
+func (r Rune) MarshalJSON() ([]byte, error) {
+	if r.Sha256 == nil {
+		return nil, fmt.Errorf("rune has no authcode %w", ErrInvalidRune)
+	}
+
+	tmp := runeJSON{
+		AuthCode:     hex.EncodeToString(r.GetAuthCode()),
+		Restrictions: make([][]Alternative, 0, len(r.Restrictions)),
+	}
+
+	id := r.getID()
+	if id != "" {
+		split := strings.SplitN(id, "-", 2)
+		tmp.UniqueID = split[0]
+		if len(split) > 1 {
+			tmp.Version = split[1]
+		}
+	}
+
+	for _, restriction := range r.Restrictions {
+		tmp.Restrictions = append(tmp.Restrictions, restriction.Alternatives)
+	}
+
+	return json.Marshal(tmp)
+}
+
+// UnmarshalJSON decodes the rune from structured JSON form or from base64 string
+func (r *Rune) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		var str string
+		err := json.Unmarshal(data, &str)
+		if err != nil {
+			return err
+		}
+
+		ret, err := FromBase64(str)
+		if err != nil {
+			return err
+		}
+		*r = *ret
+
+		return nil
+	}
+
+	var tmp runeJSON
+	err := json.Unmarshal(data, &tmp)
+	if err != nil {
+		return err
+	}
+
+	authcode, err := hex.DecodeString(tmp.AuthCode)
+	if err != nil || len(authcode) != OutputSize {
+		return fmt.Errorf("authcode must be %d hex encoded bytes %w", OutputSize, ErrInvalidRune)
+	}
+
+	restrictions := make([]Restriction, 0, len(tmp.Restrictions))
+	for i, alternatives := range tmp.Restrictions {
+		restriction, err := checkRestriction(alternatives, i == 0)
+		if err != nil {
+			return err
+		}
+		restrictions = append(restrictions, *restriction)
+	}
+
+	// Recompute the length state
+	ret, err := FromAuthCode(authcode, restrictions)
+	if err != nil {
+		return err
+	}
+
+	if tmp.UniqueID != "" || tmp.Version != "" {
+		expected := tmp.UniqueID
+		if tmp.Version != "" {
+			expected += "-" + tmp.Version
+		}
+		if ret.getID() != expected {
+			return fmt.Errorf("unique_id and version do not match restrictions %w", ErrInvalidRune)
+		}
+	}
+
+	*r = *ret
+
+	return nil
+}
+
+// MarshalJSON encodes the rune as base64 string
+func (r CompactRune) MarshalJSON() ([]byte, error) {
+	rune := Rune(r)
+	return json.Marshal(rune.ToBase64())
+}
+
+// UnmarshalJSON decodes the rune from base64 string
+func (r *CompactRune) UnmarshalJSON(data []byte) error {
+	var str string
+	err := json.Unmarshal(data, &str)
+	if err != nil {
+		return err
+	}
+
+	ret, err := FromBase64(str)
+	if err != nil {
+		return err
+	}
+	*r = CompactRune(*ret)
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package runes
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuneJSON(t *testing.T) {
+	rune := MustGetFromBase64("tU-RLjMiDpY2U0o3W1oFowar36RFGpWloPbW9-RuZdo9MyZpZD0wMjRiOWExZmE4ZTAwNmYxZTM5MzdmNjVmNjZjNDA4ZTZkYThlMWNhNzI4ZWE0MzIyMmE3MzgxZGYxY2M0NDk2MDUmbWV0aG9kPWxpc3RwZWVycyZwbnVtPTEmcG5hbWVpZF4wMjRiOWExZmE4ZTAwNmYxZTM5M3xwYXJyMF4wMjRiOWExZmE4ZTAwNmYxZTM5MyZ0aW1lPDE2NTY5MjA1MzgmcmF0ZT0y")
+	restricted := rune.MustGetRestrictedFromString("a=b\\&c|d<5")
+
+	b, err := json.Marshal(restricted)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(b), "Sha256")
+	assert.Contains(t, string(b), `"unique_id":"3"`)
+	assert.Contains(t, string(b), `[{"field":"method","cond":"=","value":"listpeers"}]`)
+	// encoding/json escapes HTML characters
+	assert.Contains(t, string(b), `[{"field":"a","cond":"=","value":"b\u0026c"},{"field":"d","cond":"\u003c","value":"5"}]`)
+
+	var decoded Rune
+	err = json.Unmarshal(b, &decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, restricted.String(), decoded.String())
+	assert.Equal(t, restricted.Sha256.GetLen(), decoded.Sha256.GetLen())
+
+	// Length state is recomputed so decoded rune can be restricted further
+	further := decoded.MustGetRestrictedFromString("x=1")
+	expected := restricted.MustGetRestrictedFromString("x=1")
+	assert.Equal(t, expected.String(), further.String())
+
+	// Compact form
+	b, err = json.Marshal(CompactRune(restricted))
+	assert.NoError(t, err)
+	assert.Equal(t, `"`+restricted.ToBase64()+`"`, string(b))
+
+	var compact CompactRune
+	err = json.Unmarshal(b, &compact)
+	assert.NoError(t, err)
+	assert.Equal(t, restricted.String(), (*Rune)(&compact).String())
+
+	// Rune also accepts compact form
+	err = json.Unmarshal(b, &decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, restricted.String(), decoded.String())
+}
+
+func TestRuneJSONVersion(t *testing.T) {
+	var secret [16]byte
+	master, err := MakeMasterRune(secret[:], 5, 2, nil)
+	assert.NoError(t, err)
+
+	b, err := json.Marshal(master)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(b), "SeedSecret")
+	assert.Contains(t, string(b), `"unique_id":"5","version":"2"`)
+
+	var decoded Rune
+	assert.NoError(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, 2, decoded.GetVersion())
+}
+
+func TestRuneJSONInvalid(t *testing.T) {
+	var decoded Rune
+	for _, str := range []string{
+		`{"authcode":"00","restrictions":[]}`,
+		`{"authcode":"` + zeroAuthCode + `","restrictions":[[]]}`,
+		`{"authcode":"` + zeroAuthCode + `","restrictions":[[{"field":"a","cond":"@","value":"1"}]]}`,
+		`{"authcode":"` + zeroAuthCode + `","restrictions":[[{"field":"a.b","cond":"=","value":"1"}]]}`,
+		`{"authcode":"` + zeroAuthCode + `","restrictions":[[{"field":"a","cond":"=","value":"1"}],[{"field":"","cond":"=","value":"1"}]]}`,
+		`{"authcode":"` + zeroAuthCode + `","unique_id":"4","restrictions":[[{"field":"","cond":"=","value":"1"}]]}`,
+		`"!!!"`,
+	} {
+		err := json.Unmarshal([]byte(str), &decoded)
+		assert.Error(t, err, str)
+	}
+}
+
+func TestRuneJSONZero(t *testing.T) {
+	_, err := json.Marshal(Rune{})
+	assert.ErrorIs(t, err, ErrInvalidRune)
+
+	_, err = json.Marshal(&Rune{Restrictions: MustMakeRestrictionsFromString("method=pay")})
+	assert.ErrorIs(t, err, ErrInvalidRune)
+}
+
+func TestRestrictionJSON(t *testing.T) {
+	restriction := MustMakeRestrictionsFromString("method^list|method^get")[0]
+
+	b, err := json.Marshal(restriction)
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"field":"method","cond":"^","value":"list"},{"field":"method","cond":"^","value":"get"}]`, string(b))
+
+	var decoded Restriction
+	assert.NoError(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, restriction, decoded)
+
+	a, err := MakeAlternative("num", "<", 5, false)
+	assert.NoError(t, err)
+	b, err = json.Marshal(a)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"field":"num","cond":"\u003c","value":"5"}`, string(b))
+}
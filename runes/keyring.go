@@ -0,0 +1,266 @@
+package runes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrDuplicateKey represents an error where key id is already used
+	ErrDuplicateKey = errors.New("duplicate key id")
+	// ErrNoSuchKey represents an error where key id is not known
+	ErrNoSuchKey = errors.New("no such key")
+	// ErrNoActiveKey represents an error where there is no key to issue runes with
+	ErrNoActiveKey = errors.New("no active key")
+)
+
+// KeyState is the state of a key in keyring
+type KeyState int
+
+const (
+	// KeyActive keys can issue and verify runes
+	KeyActive KeyState = iota
+	// KeyVerifyOnly keys can only verify already issued runes
+	KeyVerifyOnly
+	// KeyRetired keys are no longer accepted
+	KeyRetired
+)
+
+// String returns a string representation
+func (s KeyState) String() string {
+	switch s {
+	case KeyActive:
+		return "active"
+	case KeyVerifyOnly:
+		return "verify-only"
+	case KeyRetired:
+		return "retired"
+	default:
+		return fmt.Sprintf("unknown state %d", int(s))
+	}
+}
+
+type keyringEntry struct {
+	master       *MasterRune
+	state        KeyState
+	verifyOnlyAt time.Time
+	retireAt     time.Time
+}
+
+func (e *keyringEntry) stateAt(now time.Time) KeyState {
+	state := e.state
+	if !e.verifyOnlyAt.IsZero() && !now.Before(e.verifyOnlyAt) && state < KeyVerifyOnly {
+		state = KeyVerifyOnly
+	}
+	if !e.retireAt.IsZero() && !now.Before(e.retireAt) {
+		state = KeyRetired
+	}
+
+	return state
+}
+
+// Keyring holds several master secrets identified by key id. The key id is carried as version
+// of the rune's unique id restriction (runes without version belong to key 0), so secrets can be
+// rotated without invalidating all outstanding runes at once. It is safe for concurrent use.
+type Keyring struct {
+	mutex   sync.RWMutex
+	keys    map[int]*keyringEntry
+	primary int
+	now     func() time.Time
+}
+
+// NewKeyring creates a new empty keyring
+func NewKeyring() *Keyring {
+	return &Keyring{
+		keys:    make(map[int]*keyringEntry),
+		primary: -1,
+		now:     time.Now,
+	}
+}
+
+// AddKey adds a new active key which becomes the one used for issuing runes
+func (k *Keyring) AddKey(id int, secret []byte) error {
+	if id < 0 {
+		return fmt.Errorf("key id must not be negative")
+	}
+
+	master, err := MakeMasterRune(secret, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if _, ok := k.keys[id]; ok {
+		return ErrDuplicateKey
+	}
+
+	k.keys[id] = &keyringEntry{master: master, state: KeyActive}
+	k.primary = id
+
+	return nil
+}
+
+// SetKeyState changes state of key immediately
+func (k *Keyring) SetKeyState(id int, state KeyState) error {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	entry, ok := k.keys[id]
+	if !ok {
+		return ErrNoSuchKey
+	}
+	entry.state = state
+
+	return nil
+}
+
+// ScheduleKey schedules key to become verify-only and later retired (zero time means never)
+func (k *Keyring) ScheduleKey(id int, verifyOnlyAt, retireAt time.Time) error {
+	if !verifyOnlyAt.IsZero() && !retireAt.IsZero() && retireAt.Before(verifyOnlyAt) {
+		return fmt.Errorf("key cannot be retired before it becomes verify-only")
+	}
+
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	entry, ok := k.keys[id]
+	if !ok {
+		return ErrNoSuchKey
+	}
+	entry.verifyOnlyAt = verifyOnlyAt
+	entry.retireAt = retireAt
+
+	return nil
+}
+
+// KeyState returns the current state of key
+func (k *Keyring) KeyState(id int) (KeyState, error) {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+
+	entry, ok := k.keys[id]
+	if !ok {
+		return KeyRetired, ErrNoSuchKey
+	}
+
+	return entry.stateAt(k.now()), nil
+}
+
+// Issue creates a new rune with the primary key
+func (k *Keyring) Issue(uniqueid any, restrictions []Restriction) (*Rune, error) {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+
+	entry, ok := k.keys[k.primary]
+	if !ok || entry.stateAt(k.now()) != KeyActive {
+		return nil, ErrNoActiveKey
+	}
+
+	var version any
+	if k.primary != 0 {
+		version = k.primary
+	}
+
+	id, err := UniqueID(uniqueid, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry.master.GetRestricted(append([]Restriction{*id}, restrictions...)...)
+}
+
+// KeyID returns the key id referenced by rune (the version of its unique id, 0 when there is none).
+// A version that is not a canonical non-negative integer is rejected instead of falling back to key 0.
+func (k *Keyring) KeyID(rune *Rune) (int, error) {
+	split := strings.SplitN(rune.getID(), "-", 2)
+	if len(split) < 2 {
+		return 0, nil
+	}
+
+	id, err := strconv.Atoi(split[1])
+	if err != nil || id < 0 || strconv.Itoa(id) != split[1] {
+		return 0, errAuthMalformed
+	}
+
+	return id, nil
+}
+
+func (k *Keyring) masterFor(rune *Rune) (*MasterRune, error) {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+
+	id, err := k.KeyID(rune)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, ok := k.keys[id]
+	if !ok {
+		return nil, &AuthError{Reason: AuthUnknownKey}
+	}
+	if entry.stateAt(k.now()) == KeyRetired {
+		return nil, &AuthError{Reason: AuthRetiredKey}
+	}
+
+	return entry.master, nil
+}
+
+// VerifyRune checks whether rune was issued by a (non-retired) key from keyring
+func (k *Keyring) VerifyRune(rune *Rune) error {
+	if rune == nil {
+		return &AuthError{Reason: AuthNilRune}
+	}
+
+	master, err := k.masterFor(rune)
+	if err != nil {
+		return err
+	}
+
+	return master.VerifyAuthCode(rune.GetAuthCode(), rune.Restrictions)
+}
+
+// IsRuneAuthorized check whether rune is authorized
+func (k *Keyring) IsRuneAuthorized(rune *Rune) bool {
+	return k.VerifyRune(rune) == nil
+}
+
+// Check checks rune
+func (k *Keyring) Check(rune *Rune, vals map[string]any) error {
+	return k.CheckContext(context.Background(), rune, MapValues(vals), EvalOptions{})
+}
+
+// CheckContext checks that rune was issued by keyring and then evaluates it like Rune.CheckContext
+func (k *Keyring) CheckContext(ctx context.Context, rune *Rune, vals Values, opts EvalOptions) error {
+	err := k.VerifyRune(rune)
+	if err != nil {
+		return err
+	}
+
+	// Keyring understands the version (it is the key id) so unique id restriction is satisfied
+	if id := rune.getID(); id != "" {
+		vals = withID{Values: vals, id: id}
+	}
+
+	return rune.CheckContext(ctx, vals, opts)
+}
+
+// withID adds unique id field to values
+type withID struct {
+	Values
+	id string
+}
+
+// Lookup returns the value of field
+func (w withID) Lookup(field string) (any, bool) {
+	if field == "" {
+		return w.id, true
+	}
+	return w.Values.Lookup(field)
+}
@@ -0,0 +1,126 @@
+package runes
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyringRotation(t *testing.T) {
+	keyring := NewKeyring()
+
+	_, err := keyring.Issue(1, nil)
+	assert.ErrorIs(t, err, ErrNoActiveKey)
+
+	assert.NoError(t, keyring.AddKey(0, []byte("old secret")))
+	old, err := keyring.Issue(1, MustMakeRestrictionsFromString("method^list"))
+	assert.NoError(t, err)
+	id, err := keyring.KeyID(old)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, id)
+
+	// Runes issued directly from the old master (without version) belong to key 0
+	legacyMaster := MustMakeMasterRune([]byte("old secret"))
+	legacy := legacyMaster.MustGetRestrictedFromString("method^list")
+	assert.Equal(t, true, keyring.IsRuneAuthorized(&legacy))
+
+	assert.NoError(t, keyring.AddKey(1, []byte("new secret")))
+	assert.ErrorIs(t, keyring.AddKey(1, []byte("other secret")), ErrDuplicateKey)
+
+	fresh, err := keyring.Issue(2, MustMakeRestrictionsFromString("method^list"))
+	assert.NoError(t, err)
+	id, err = keyring.KeyID(fresh)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, id)
+	assert.Equal(t, 2, fresh.GetUniqueID())
+
+	vals := map[string]any{"method": "listpeers"}
+	assert.NoError(t, keyring.Check(old, vals))
+	assert.NoError(t, keyring.Check(fresh, vals))
+	assert.Error(t, keyring.Check(fresh, map[string]any{"method": "pay"}))
+	// Callers map is not modified
+	assert.Equal(t, 1, len(vals))
+
+	// Old key can only verify
+	assert.NoError(t, keyring.SetKeyState(0, KeyVerifyOnly))
+	assert.NoError(t, keyring.Check(old, vals))
+
+	// Retired key is rejected
+	assert.NoError(t, keyring.SetKeyState(0, KeyRetired))
+	err = keyring.Check(old, vals)
+	assert.ErrorIs(t, err, ErrUnauthorizedRune)
+	var authErr *AuthError
+	assert.True(t, errors.As(err, &authErr))
+	assert.Equal(t, AuthRetiredKey, authErr.Reason)
+
+	// Rune claiming a key that does not exist
+	unknown := MustMakeMasterRune([]byte("new secret"))
+	forged, err := unknown.GetRestricted(*mustUniqueID(t, 3, 7))
+	assert.NoError(t, err)
+	err = keyring.Check(forged, vals)
+	assert.True(t, errors.As(err, &authErr))
+	assert.Equal(t, AuthUnknownKey, authErr.Reason)
+
+	// Tampered rune with valid key id
+	fake := MustGetFromString(fresh.String() + "|method^get")
+	assert.ErrorIs(t, keyring.Check(&fake, vals), ErrUnauthorizedRune)
+
+	assert.ErrorIs(t, keyring.SetKeyState(5, KeyRetired), ErrNoSuchKey)
+}
+
+func TestKeyringSchedule(t *testing.T) {
+	keyring := NewKeyring()
+	now := time.Unix(1700000000, 0)
+	keyring.now = func() time.Time { return now }
+
+	assert.NoError(t, keyring.AddKey(3, []byte("secret")))
+	issued, err := keyring.Issue(1, nil)
+	assert.NoError(t, err)
+
+	assert.Error(t, keyring.ScheduleKey(3, now.Add(time.Hour), now))
+	assert.NoError(t, keyring.ScheduleKey(3, now.Add(time.Hour), now.Add(2*time.Hour)))
+
+	state, err := keyring.KeyState(3)
+	assert.NoError(t, err)
+	assert.Equal(t, KeyActive, state)
+
+	now = now.Add(time.Hour)
+	state, _ = keyring.KeyState(3)
+	assert.Equal(t, KeyVerifyOnly, state)
+	_, err = keyring.Issue(2, nil)
+	assert.ErrorIs(t, err, ErrNoActiveKey)
+	assert.NoError(t, keyring.Check(issued, nil))
+
+	now = now.Add(time.Hour)
+	state, _ = keyring.KeyState(3)
+	assert.Equal(t, KeyRetired, state)
+	assert.ErrorIs(t, keyring.Check(issued, nil), ErrUnauthorizedRune)
+}
+
+func TestKeyringMalformedVersion(t *testing.T) {
+	keyring := NewKeyring()
+	assert.NoError(t, keyring.AddKey(0, []byte("secret")))
+	master := MustMakeMasterRune([]byte("secret"))
+
+	for _, version := range []string{"abc", "", "1-2", "-1", "+0", "00"} {
+		id := &Restriction{Alternatives: []Alternative{{Field: "", Cond: "=", Value: "1-" + version}}}
+		r, err := master.GetRestricted(*id)
+		assert.NoError(t, err, version)
+
+		_, err = keyring.KeyID(r)
+		var authErr *AuthError
+		if assert.True(t, errors.As(err, &authErr), version) {
+			assert.Equal(t, AuthMalformed, authErr.Reason, version)
+		}
+		// Not checked against key 0 even though it was signed by it
+		assert.ErrorIs(t, keyring.VerifyRune(r), ErrUnauthorizedRune, version)
+	}
+}
+
+func mustUniqueID(t *testing.T, id, version any) *Restriction {
+	r, err := UniqueID(id, version)
+	assert.NoError(t, err)
+	return r
+}
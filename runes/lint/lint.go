@@ -0,0 +1,271 @@
+// Package lint finds common mistakes in rune restrictions.
+package lint
+
+import (
+	"fmt"
+	"sort"
+
+	runes "github.com/bolt-observer/go-runes/runes"
+)
+
+// Severity of a finding
+type Severity int
+
+const (
+	// SeverityInfo is used for harmless findings
+	SeverityInfo Severity = iota
+	// SeverityWarning is used for likely mistakes
+	SeverityWarning
+	// SeverityError is used for certain mistakes
+	SeverityError
+)
+
+// String returns the name of severity
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+}
+
+// Finding is a single problem found by a check
+type Finding struct {
+	// Check is the name of the check that reported the finding
+	Check string
+	// Severity of the finding
+	Severity Severity
+	// Restriction is the index of the restriction (-1 when the finding is about all restrictions)
+	Restriction int
+	// Alternative is the index of the alternative (-1 when the finding is about the whole restriction)
+	Alternative int
+	// Message describes the problem
+	Message string
+}
+
+// String returns a human readable representation of finding
+func (f Finding) String() string {
+	position := "restrictions"
+	if f.Restriction >= 0 {
+		position = fmt.Sprintf("restriction %d", f.Restriction)
+		if f.Alternative >= 0 {
+			position += fmt.Sprintf(" alternative %d", f.Alternative)
+		}
+	}
+
+	return fmt.Sprintf("%s: %s: %s (%s)", position, f.Severity, f.Message, f.Check)
+}
+
+// Check is a single lint check
+type Check struct {
+	// Name identifies the check
+	Name string
+	// Severity is assigned to all findings of the check
+	Severity Severity
+	// Run returns findings, Check and Severity are filled in by Lint
+	Run func(restrictions []runes.Restriction) []Finding
+}
+
+// DefaultChecks returns all available checks with their default severities
+func DefaultChecks() []Check {
+	return []Check{
+		LexicographicComparison(),
+		NonNumericComparison(),
+		TimeUpperBound("time"),
+		Duplicate(),
+		Redundant(),
+	}
+}
+
+// Lint runs checks over restrictions
+func Lint(restrictions []runes.Restriction, checks []Check) []Finding {
+	ret := make([]Finding, 0)
+	for _, check := range checks {
+		for _, finding := range check.Run(restrictions) {
+			finding.Check = check.Name
+			finding.Severity = check.Severity
+			ret = append(ret, finding)
+		}
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].Restriction != ret[j].Restriction {
+			return ret[i].Restriction < ret[j].Restriction
+		}
+		return ret[i].Alternative < ret[j].Alternative
+	})
+
+	return ret
+}
+
+// HasErrors reports whether any of the findings is an error
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity >= SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// LexicographicComparison reports { and } used with numeric literals where < and > was probably meant
+func LexicographicComparison() Check {
+	return Check{
+		Name:     "lexicographic-comparison",
+		Severity: SeverityWarning,
+		Run: func(restrictions []runes.Restriction) []Finding {
+			ret := make([]Finding, 0)
+			for i, r := range restrictions {
+				for j, alt := range r.Alternatives {
+					if (alt.Cond != "{" && alt.Cond != "}") || !alt.IsNumeric() {
+						continue
+					}
+					numeric := "<"
+					if alt.Cond == "}" {
+						numeric = ">"
+					}
+					ret = append(ret, Finding{
+						Restriction: i,
+						Alternative: j,
+						Message:     fmt.Sprintf("%s compares strings, did you mean %s%s%v?", alt.String(), alt.Field, numeric, alt.Value),
+					})
+				}
+			}
+			return ret
+		},
+	}
+}
+
+// NonNumericComparison reports < and > used with literals that are not numbers (such alternatives never pass)
+func NonNumericComparison() Check {
+	return Check{
+		Name:     "non-numeric-comparison",
+		Severity: SeverityError,
+		Run: func(restrictions []runes.Restriction) []Finding {
+			ret := make([]Finding, 0)
+			for i, r := range restrictions {
+				for j, alt := range r.Alternatives {
+					if (alt.Cond != "<" && alt.Cond != ">") || alt.IsNumeric() {
+						continue
+					}
+					ret = append(ret, Finding{
+						Restriction: i,
+						Alternative: j,
+						Message:     fmt.Sprintf("%s compares against non-numeric %q and never passes", alt.String(), fmt.Sprintf("%v", alt.Value)),
+					})
+				}
+			}
+			return ret
+		},
+	}
+}
+
+// TimeUpperBound reports restrictions that never expire, i.e. there is no restriction requiring field < N
+func TimeUpperBound(field string) Check {
+	return Check{
+		Name:     "time-upper-bound",
+		Severity: SeverityWarning,
+		Run: func(restrictions []runes.Restriction) []Finding {
+			for _, r := range restrictions {
+				bounded := len(r.Alternatives) > 0
+				for _, alt := range r.Alternatives {
+					if alt.Field != field || alt.Cond != "<" || !alt.IsNumeric() {
+						bounded = false
+						break
+					}
+				}
+				if bounded {
+					return nil
+				}
+			}
+
+			return []Finding{{
+				Restriction: -1,
+				Alternative: -1,
+				Message:     fmt.Sprintf("no upper bound on %s, rune never expires", field),
+			}}
+		},
+	}
+}
+
+// Duplicate reports restrictions and alternatives that are repeated
+func Duplicate() Check {
+	return Check{
+		Name:     "duplicate",
+		Severity: SeverityWarning,
+		Run: func(restrictions []runes.Restriction) []Finding {
+			ret := make([]Finding, 0)
+			seen := make(map[string]int)
+			for i, r := range restrictions {
+				str := r.String()
+				if first, ok := seen[str]; ok {
+					ret = append(ret, Finding{
+						Restriction: i,
+						Alternative: -1,
+						Message:     fmt.Sprintf("duplicates restriction %d", first),
+					})
+					continue
+				}
+				seen[str] = i
+
+				alternatives := make(map[string]int)
+				for j, alt := range r.Alternatives {
+					str := alt.String()
+					if first, ok := alternatives[str]; ok {
+						ret = append(ret, Finding{
+							Restriction: i,
+							Alternative: j,
+							Message:     fmt.Sprintf("duplicates alternative %d", first),
+						})
+						continue
+					}
+					alternatives[str] = j
+				}
+			}
+			return ret
+		},
+	}
+}
+
+// Redundant reports restrictions that are already implied by another restriction
+func Redundant() Check {
+	return Check{
+		Name:     "redundant",
+		Severity: SeverityInfo,
+		Run: func(restrictions []runes.Restriction) []Finding {
+			ret := make([]Finding, 0)
+			for j, rj := range restrictions {
+				if tautology, err := runes.Implies(nil, restrictions[j:j+1]); err != nil || tautology {
+					continue
+				}
+
+				for i, ri := range restrictions {
+					if i == j || ri.String() == rj.String() {
+						continue
+					}
+					implied, err := runes.Implies(restrictions[i:i+1], restrictions[j:j+1])
+					if err != nil || !implied {
+						continue
+					}
+					// Equivalent restrictions are reported only once
+					if reverse, _ := runes.Implies(restrictions[j:j+1], restrictions[i:i+1]); reverse && j < i {
+						continue
+					}
+
+					ret = append(ret, Finding{
+						Restriction: j,
+						Alternative: -1,
+						Message:     fmt.Sprintf("is implied by restriction %d (%s)", i, ri.String()),
+					})
+					break
+				}
+			}
+			return ret
+		},
+	}
+}
@@ -0,0 +1,76 @@
+package lint
+
+import (
+	"testing"
+
+	runes "github.com/bolt-observer/go-runes/runes"
+	"github.com/stretchr/testify/assert"
+)
+
+func findings(t *testing.T, str string, checks ...Check) []Finding {
+	restrictions, err := runes.MakeRestrictionsFromString(str)
+	assert.NoError(t, err)
+	return Lint(restrictions, checks)
+}
+
+func TestLexicographicComparison(t *testing.T) {
+	f := findings(t, "method=pay&time}100|time{abc", LexicographicComparison())
+	assert.Equal(t, []Finding{{Check: "lexicographic-comparison", Severity: SeverityWarning, Restriction: 1, Alternative: 0, Message: "time}100 compares strings, did you mean time>100?"}}, f)
+}
+
+func TestNonNumericComparison(t *testing.T) {
+	f := findings(t, "time<100&method=pay|time>abc", NonNumericComparison())
+	if assert.Len(t, f, 1) {
+		assert.Equal(t, SeverityError, f[0].Severity)
+		assert.Equal(t, 1, f[0].Restriction)
+		assert.Equal(t, 1, f[0].Alternative)
+	}
+	assert.True(t, HasErrors(f))
+}
+
+func TestTimeUpperBound(t *testing.T) {
+	assert.Empty(t, findings(t, "method=pay&time<100", TimeUpperBound("time")))
+	assert.Empty(t, findings(t, "time<100|time<200", TimeUpperBound("time")))
+
+	for _, str := range []string{"method=pay", "time<100|method=pay", "time>100", "time<abc"} {
+		f := findings(t, str, TimeUpperBound("time"))
+		if assert.Len(t, f, 1, str) {
+			assert.Equal(t, -1, f[0].Restriction)
+			assert.Equal(t, "restrictions: warning: no upper bound on time, rune never expires (time-upper-bound)", f[0].String())
+		}
+	}
+
+	assert.Empty(t, findings(t, "expiry<100", TimeUpperBound("expiry")))
+}
+
+func TestDuplicate(t *testing.T) {
+	f := findings(t, "method=pay&time<100&method=pay&pnum=1|pnum=2|pnum=1", Duplicate())
+	assert.Equal(t, []Finding{
+		{Check: "duplicate", Severity: SeverityWarning, Restriction: 2, Alternative: -1, Message: "duplicates restriction 0"},
+		{Check: "duplicate", Severity: SeverityWarning, Restriction: 3, Alternative: 2, Message: "duplicates alternative 0"},
+	}, f)
+}
+
+func TestRedundant(t *testing.T) {
+	f := findings(t, "method^list&method=listpeers&time<100&time<200&note#x&method=pay&method=pay", Redundant())
+	assert.Equal(t, []Finding{
+		{Check: "redundant", Severity: SeverityInfo, Restriction: 0, Alternative: -1, Message: "is implied by restriction 1 (method=listpeers)"},
+		{Check: "redundant", Severity: SeverityInfo, Restriction: 3, Alternative: -1, Message: "is implied by restriction 2 (time<100)"},
+	}, f)
+
+	// Equivalent restrictions are reported once
+	f = findings(t, "time<100|method=pay&method=pay|time<100", Redundant())
+	if assert.Len(t, f, 1) {
+		assert.Equal(t, 1, f[0].Restriction)
+	}
+}
+
+func TestLintOrder(t *testing.T) {
+	f := findings(t, "time}1&time<abc&time}1", DefaultChecks()...)
+	positions := make([]int, 0)
+	for _, finding := range f {
+		positions = append(positions, finding.Restriction)
+	}
+	assert.Equal(t, []int{-1, 0, 1, 2, 2}, positions)
+	assert.True(t, HasErrors(f))
+}
@@ -1,7 +1,8 @@
 package runes
 
 import (
-	"encoding/hex"
+	"context"
+	"crypto/subtle"
 	"errors"
 	"fmt"
 )
@@ -65,40 +66,118 @@ func MakeMasterRune(seedsecret []byte, uniqueid, version any, restrictions []Res
 	return ret, nil
 }
 
-// IsRuneAuthorized check whether rune is authorized
-func (r *MasterRune) IsRuneAuthorized(other *Rune) bool {
-	if other == nil {
-		return false
+// AuthErrorReason describes why an auth code was rejected
+type AuthErrorReason int
+
+const (
+	// AuthNilRune means there was no rune to verify
+	AuthNilRune AuthErrorReason = iota
+	// AuthMalformed means the auth code has the wrong length or the rune version is not a valid key id
+	AuthMalformed
+	// AuthMismatch means the auth code does not match the restrictions
+	AuthMismatch
+	// AuthUnknownKey means the rune references a key that is not known
+	AuthUnknownKey
+	// AuthRetiredKey means the rune references a key that was retired
+	AuthRetiredKey
+)
+
+// String returns a string representation
+func (r AuthErrorReason) String() string {
+	switch r {
+	case AuthNilRune:
+		return "nil rune"
+	case AuthMalformed:
+		return "malformed auth code"
+	case AuthMismatch:
+		return "auth code mismatch"
+	case AuthUnknownKey:
+		return "unknown key"
+	case AuthRetiredKey:
+		return "retired key"
+	default:
+		return fmt.Sprintf("unknown reason %d", int(r))
 	}
+}
+
+// AuthError is returned when verification of an auth code fails
+type AuthError struct {
+	Reason AuthErrorReason
+}
+
+// Error returns the error message
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrUnauthorizedRune, e.Reason)
+}
+
+// Unwrap makes errors.Is(err, ErrUnauthorizedRune) work
+func (e *AuthError) Unwrap() error {
+	return ErrUnauthorizedRune
+}
+
+// Preallocated so that failing verification does not allocate and take longer than succeeding one
+var (
+	errAuthNilRune   = &AuthError{Reason: AuthNilRune}
+	errAuthMalformed = &AuthError{Reason: AuthMalformed}
+	errAuthMismatch  = &AuthError{Reason: AuthMismatch}
+)
+
+// VerifyAuthCode checks whether authcode was derived from this master rune and restrictions.
+// The comparison is done in constant time and the hash is always computed over all restrictions.
+func (r *MasterRune) VerifyAuthCode(authcode []byte, restrictions []Restriction) error {
+	if len(authcode) != OutputSize {
+		return errAuthMalformed
+	}
+
 	hasher := NewSha256()
 	hasher.Write([]byte(r.SeedSecret))
 	hasher.AddPadding()
 
-	if len(r.Restrictions) > len(other.Restrictions) {
-		// Master cannot have more restrictions
-		return false
-	}
-
-	for _, restriction := range other.Restrictions {
+	for _, restriction := range restrictions {
 		hasher.Write([]byte(restriction.String()))
 		hasher.AddPadding()
 	}
 
-	sum := hasher.GetSum()
+	return r.compareAuthCode(hasher.GetSum(), authcode, len(restrictions))
+}
+
+// compareAuthCode compares computed sum with authcode in constant time
+func (r *MasterRune) compareAuthCode(sum [OutputSize]byte, authcode []byte, numRestrictions int) error {
+	// Master cannot have more restrictions
+	ok := subtle.ConstantTimeLessOrEq(len(r.Restrictions), numRestrictions)
+	ok &= subtle.ConstantTimeCompare(sum[:], authcode)
+
+	if ok != 1 {
+		return errAuthMismatch
+	}
+
+	return nil
+}
+
+// IsRuneAuthorized check whether rune is authorized
+func (r *MasterRune) IsRuneAuthorized(other *Rune) bool {
+	if other == nil {
+		return false
+	}
 
-	return hex.EncodeToString(sum[:]) == hex.EncodeToString(other.GetAuthCode())
+	return r.VerifyAuthCode(other.GetAuthCode(), other.Restrictions) == nil
 }
 
 // Check checks rune
 func (r *MasterRune) Check(rune *Rune, vals map[string]any) error {
-	if !r.IsRuneAuthorized(rune) {
-		return ErrUnauthorizedRune
+	return r.CheckContext(context.Background(), rune, MapValues(vals), EvalOptions{})
+}
+
+// CheckContext checks that rune is authorized and then evaluates it like Rune.CheckContext
+func (r *MasterRune) CheckContext(ctx context.Context, rune *Rune, vals Values, opts EvalOptions) error {
+	if rune == nil {
+		return errAuthNilRune
 	}
 
-	ok, msg := rune.Evaluate(vals)
-	if ok {
-		return nil
+	err := r.VerifyAuthCode(rune.GetAuthCode(), rune.Restrictions)
+	if err != nil {
+		return err
 	}
 
-	return fmt.Errorf(msg)
+	return rune.CheckContext(ctx, vals, opts)
 }
@@ -3,8 +3,13 @@ package runes
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
+	"math"
+	"os"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -109,3 +114,155 @@ func TestRealRune(t *testing.T) {
 
 	assert.Equal(t, restricted2.String(), fresh2.String())
 }
+
+func TestVerifyAuthCode(t *testing.T) {
+	secret := make([]byte, 55)
+	_, err := rand.Read(secret)
+	assert.NoError(t, err)
+
+	master := MustMakeMasterRune(secret)
+	restricted := master.MustGetRestrictedFromString("method^list|method^get&time<100")
+
+	err = master.VerifyAuthCode(restricted.GetAuthCode(), restricted.Restrictions)
+	assert.NoError(t, err)
+
+	var authErr *AuthError
+
+	err = master.VerifyAuthCode(restricted.GetAuthCode()[:31], restricted.Restrictions)
+	assert.ErrorIs(t, err, ErrUnauthorizedRune)
+	assert.True(t, errors.As(err, &authErr))
+	assert.Equal(t, AuthMalformed, authErr.Reason)
+
+	err = master.VerifyAuthCode(restricted.GetAuthCode(), restricted.Restrictions[:1])
+	assert.ErrorIs(t, err, ErrUnauthorizedRune)
+	assert.True(t, errors.As(err, &authErr))
+	assert.Equal(t, AuthMismatch, authErr.Reason)
+
+	// Rejections are *AuthError now, they can no longer be compared with == but still match errors.Is
+	for _, err := range []error{master.Check(nil, nil), master.Check(&Rune{Sha256: restricted.Sha256}, nil)} {
+		assert.ErrorIs(t, err, ErrUnauthorizedRune)
+		assert.NotEqual(t, ErrUnauthorizedRune, err)
+	}
+
+	err = master.Check(nil, nil)
+	assert.True(t, errors.As(err, &authErr))
+	assert.Equal(t, AuthNilRune, authErr.Reason)
+}
+
+// welchT returns Welch's t statistic of two samples
+func welchT(a, b []float64) float64 {
+	meanVar := func(x []float64) (float64, float64) {
+		mean := 0.0
+		for _, v := range x {
+			mean += v
+		}
+		mean /= float64(len(x))
+
+		variance := 0.0
+		for _, v := range x {
+			variance += (v - mean) * (v - mean)
+		}
+		return mean, variance / float64(len(x)-1)
+	}
+
+	meanA, varA := meanVar(a)
+	meanB, varB := meanVar(b)
+	return (meanA - meanB) / math.Sqrt(varA/float64(len(a))+varB/float64(len(b)))
+}
+
+// cropSamples drops samples above the given percentile of all samples (outliers caused by scheduling)
+func cropSamples(samples [][]float64, percentile float64) [][]float64 {
+	all := make([]float64, 0)
+	for _, one := range samples {
+		all = append(all, one...)
+	}
+	sort.Float64s(all)
+	limit := all[int(float64(len(all)-1)*percentile)]
+
+	ret := make([][]float64, 0, len(samples))
+	for _, one := range samples {
+		cropped := make([]float64, 0, len(one))
+		for _, v := range one {
+			if v <= limit {
+				cropped = append(cropped, v)
+			}
+		}
+		ret = append(ret, cropped)
+	}
+	return ret
+}
+
+func TestVerifyAuthCodeAllocations(t *testing.T) {
+	master := MustMakeMasterRune([]byte("secret"))
+	restricted := master.MustGetRestrictedFromString("method^list|method^get&time<100")
+
+	good := restricted.GetAuthCode()
+	bad := append([]byte{}, good...)
+	bad[0] ^= 0xff
+
+	// Failing verification must do the same work as succeeding one, in particular it must not allocate an error
+	goodAllocs := testing.AllocsPerRun(100, func() {
+		_ = master.VerifyAuthCode(good, restricted.Restrictions)
+	})
+	badAllocs := testing.AllocsPerRun(100, func() {
+		_ = master.VerifyAuthCode(bad, restricted.Restrictions)
+	})
+	assert.Equal(t, goodAllocs, badAllocs)
+}
+
+// TestVerifyAuthCodeTiming measures wall-clock time, so it only runs when RUNES_TIMING_TEST is set
+func TestVerifyAuthCodeTiming(t *testing.T) {
+	if testing.Short() || os.Getenv("RUNES_TIMING_TEST") == "" {
+		t.Skip("timing test only runs with RUNES_TIMING_TEST=1")
+	}
+
+	secret := make([]byte, 55)
+	_, err := rand.Read(secret)
+	assert.NoError(t, err)
+
+	master := MustMakeMasterRune(secret)
+	restricted := master.MustGetRestrictedFromString("method^list|method^get&time<100")
+
+	good := restricted.GetAuthCode()
+	badFirst := append([]byte{}, good...)
+	badFirst[0] ^= 0xff
+	badLast := append([]byte{}, good...)
+	badLast[len(badLast)-1] ^= 0xff
+	codes := [][]byte{good, badFirst, badLast}
+
+	const (
+		rounds = 3000
+		batch  = 10
+		// Threshold commonly used by leakage detection tools (dudect)
+		threshold = 4.5
+	)
+
+	// Warm up
+	for i := 0; i < rounds; i++ {
+		master.VerifyAuthCode(codes[i%len(codes)], restricted.Restrictions)
+	}
+
+	// Interleave measurements in random order so that noise affects all classes equally
+	samples := make([][]float64, len(codes))
+	order := make([]byte, rounds)
+	_, err = rand.Read(order)
+	assert.NoError(t, err)
+	for i := 0; i < rounds; i++ {
+		class := int(order[i]) % len(codes)
+		code := codes[class]
+		start := time.Now()
+		for j := 0; j < batch; j++ {
+			master.VerifyAuthCode(code, restricted.Restrictions)
+		}
+		samples[class] = append(samples[class], float64(time.Since(start)))
+	}
+	samples = cropSamples(samples, 0.9)
+
+	names := []string{"matching", "first byte mismatch", "last byte mismatch"}
+	for i := 0; i < len(codes); i++ {
+		for j := i + 1; j < len(codes); j++ {
+			tStat := welchT(samples[i], samples[j])
+			assert.Less(t, math.Abs(tStat), threshold, "%s vs %s: t = %.2f", names[i], names[j], tStat)
+		}
+	}
+}
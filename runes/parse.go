@@ -0,0 +1,242 @@
+package runes
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ParseErrorKind describes what kind of problem parser found
+type ParseErrorKind int
+
+const (
+	// ParseBadFormat means the rune does not have the expected overall shape
+	ParseBadFormat ParseErrorKind = iota
+	// ParseUnknownOperator means the alternative has no or an unknown operator
+	ParseUnknownOperator
+	// ParseBadField means the field name is not valid
+	ParseBadField
+	// ParseMisplacedUniqueID means the unique id is where it is not allowed or malformed
+	ParseMisplacedUniqueID
+	// ParseEmptyRestriction means the restriction has no alternatives
+	ParseEmptyRestriction
+	// ParseBadHex means the auth code is not valid hex
+	ParseBadHex
+	// ParseBadBase64 means the rune is not valid base64
+	ParseBadBase64
+	// ParseBadEscape means the value contains an unfinished escape sequence
+	ParseBadEscape
+	// ParseBadUTF8 means the restrictions are not valid UTF-8 (strict mode only)
+	ParseBadUTF8
+	// ParseBadValue means the value cannot be represented in string form (builder only)
+	ParseBadValue
+)
+
+// String returns a string representation
+func (k ParseErrorKind) String() string {
+	switch k {
+	case ParseBadFormat:
+		return "bad format"
+	case ParseUnknownOperator:
+		return "unknown operator"
+	case ParseBadField:
+		return "bad field"
+	case ParseMisplacedUniqueID:
+		return "misplaced unique id"
+	case ParseEmptyRestriction:
+		return "empty restriction"
+	case ParseBadHex:
+		return "bad hex"
+	case ParseBadBase64:
+		return "bad base64"
+	case ParseBadEscape:
+		return "bad escape"
+	case ParseBadUTF8:
+		return "bad utf-8"
+	case ParseBadValue:
+		return "bad value"
+	default:
+		return fmt.Sprintf("unknown kind %d", int(k))
+	}
+}
+
+// ParseOptions control how runes are parsed
+type ParseOptions struct {
+	// Strict mode accepts exactly what the Python and C reference implementations accept:
+	// only ASCII punctuation delimits the operator (and is forbidden in field names),
+	// restrictions must be valid UTF-8 and no whitespace is trimmed.
+	// Lenient mode (the default) treats all Unicode punctuation as operator delimiters and
+	// allows arbitrary bytes in values for backwards compatibility.
+	Strict bool
+}
+
+func (o ParseOptions) isPunct(r rune) bool {
+	if o.Strict {
+		return isASCIIPunct(r)
+	}
+
+	return isPunct(r)
+}
+
+// ParseError describes where and why parsing failed
+type ParseError struct {
+	Kind ParseErrorKind
+	// Offset is the byte offset in the parsed input (for FromBase64 restriction errors it is the offset in decoded data)
+	Offset int
+	// Restriction is the index of restriction (or -1 when not applicable)
+	Restriction int
+	// Alternative is the index of alternative inside restriction (or -1 when not applicable)
+	Alternative int
+	// Token is the offending part of input
+	Token string
+	// Msg contains additional details
+	Msg string
+}
+
+func newParseError(kind ParseErrorKind, offset int, token string, msg string) *ParseError {
+	return &ParseError{Kind: kind, Offset: offset, Restriction: -1, Alternative: -1, Token: token, Msg: msg}
+}
+
+// Error returns the error message
+func (e *ParseError) Error() string {
+	pos := fmt.Sprintf("offset %d", e.Offset)
+	if e.Restriction >= 0 {
+		pos += fmt.Sprintf(", restriction %d", e.Restriction)
+	}
+	if e.Alternative >= 0 {
+		pos += fmt.Sprintf(", alternative %d", e.Alternative)
+	}
+
+	ret := fmt.Sprintf("%v: %v at %s", ErrInvalidRune, e.Kind, pos)
+	if e.Token != "" {
+		ret += fmt.Sprintf(" (%q)", e.Token)
+	}
+	if e.Msg != "" {
+		ret += ": " + e.Msg
+	}
+
+	return ret
+}
+
+// Unwrap makes errors.Is(err, ErrInvalidRune) work
+func (e *ParseError) Unwrap() error {
+	return ErrInvalidRune
+}
+
+// shiftParseError moves the position of a parse error by offset (to make it relative to outer input)
+func shiftParseError(err error, offset int) error {
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		pe.Offset += offset
+	}
+
+	return err
+}
+
+// Format is the encoding of a rune detected by Parse
+type Format int
+
+const (
+	// FormatUnknown means format could not be detected
+	FormatUnknown Format = iota
+	// FormatHex is the "<64 hex digits>:<restrictions>" form
+	FormatHex
+	// FormatBase64URL is URL-safe base64 without padding (the canonical form)
+	FormatBase64URL
+	// FormatBase64URLPadded is URL-safe base64 with padding
+	FormatBase64URLPadded
+	// FormatBase64Std is standard alphabet base64 without padding
+	FormatBase64Std
+	// FormatBase64StdPadded is standard alphabet base64 with padding
+	FormatBase64StdPadded
+)
+
+// String returns a string representation
+func (f Format) String() string {
+	switch f {
+	case FormatHex:
+		return "hex"
+	case FormatBase64URL:
+		return "base64url"
+	case FormatBase64URLPadded:
+		return "base64url padded"
+	case FormatBase64Std:
+		return "base64"
+	case FormatBase64StdPadded:
+		return "base64 padded"
+	default:
+		return "unknown"
+	}
+}
+
+// trimRune removes surrounding whitespace and a matching pair of quotes
+func trimRune(s string) string {
+	s = strings.TrimSpace(s)
+	for _, quote := range []string{`"`, `'`, "`"} {
+		if len(s) >= 2 && strings.HasPrefix(s, quote) && strings.HasSuffix(s, quote) {
+			return strings.TrimSpace(s[1 : len(s)-1])
+		}
+	}
+
+	return s
+}
+
+// DetectFormat detects the encoding of rune (after trimming whitespace and quotes)
+func DetectFormat(s string) Format {
+	s = trimRune(s)
+
+	if len(s) >= 65 && s[64] == ':' {
+		return FormatHex
+	}
+
+	padded := strings.HasSuffix(s, "=")
+	std := strings.ContainsAny(s, "+/")
+	url := strings.ContainsAny(s, "-_")
+
+	switch {
+	case std && url:
+		return FormatUnknown
+	case std && padded:
+		return FormatBase64StdPadded
+	case std:
+		return FormatBase64Std
+	case padded:
+		return FormatBase64URLPadded
+	default:
+		return FormatBase64URL
+	}
+}
+
+// Parse parses a rune in any of the supported formats (hex, URL-safe or standard base64, with or without padding)
+// ignoring surrounding whitespace and quotes. It returns the detected format.
+func Parse(s string) (*Rune, Format, error) {
+	return ParseWithOptions(s, ParseOptions{})
+}
+
+// ParseWithOptions is like Parse but uses options
+func ParseWithOptions(s string, opts ParseOptions) (*Rune, Format, error) {
+	s = trimRune(s)
+	format := DetectFormat(s)
+
+	var (
+		ret *Rune
+		err error
+	)
+
+	switch format {
+	case FormatHex:
+		ret, err = FromStringWithOptions(s, opts)
+	case FormatBase64Std, FormatBase64StdPadded:
+		ret, err = FromBase64WithOptions(strings.NewReplacer("+", "-", "/", "_").Replace(s), opts)
+	case FormatBase64URL, FormatBase64URLPadded:
+		ret, err = FromBase64WithOptions(s, opts)
+	default:
+		err = newParseError(ParseBadBase64, 0, "", "mixed base64 alphabets")
+	}
+
+	if err != nil {
+		return nil, format, err
+	}
+
+	return ret, format, nil
+}
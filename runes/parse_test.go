@@ -0,0 +1,177 @@
+package runes
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const zeroAuthCode = "374708fff7719dd5979ec875d56cd2286f6d3cf7ec317a3b25632aab28ec37bb"
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		input       string
+		kind        ParseErrorKind
+		offset      int
+		restriction int
+		alternative int
+	}{
+		{"", ParseBadFormat, 0, -1, -1},
+		{zeroAuthCode, ParseBadFormat, 64, -1, -1},
+		{"x" + zeroAuthCode[1:] + ":", ParseBadHex, 0, -1, -1},
+		{zeroAuthCode[:10] + "g" + zeroAuthCode[11:] + ":", ParseBadHex, 10, -1, -1},
+		{zeroAuthCode + ":method", ParseUnknownOperator, 65 + 6, 0, 0},
+		{zeroAuthCode + ":a=1&method@x", ParseUnknownOperator, 65 + 10, 1, 0},
+		{zeroAuthCode + ":a=1&b=2|method@x", ParseUnknownOperator, 65 + 14, 1, 1},
+		{zeroAuthCode + ":a=1&=2", ParseMisplacedUniqueID, 65 + 4, 1, 0},
+		{zeroAuthCode + ":=1|a=2", ParseMisplacedUniqueID, 65, 0, -1},
+		{zeroAuthCode + ":<1", ParseMisplacedUniqueID, 65, 0, 0},
+		{zeroAuthCode + ":a=1&&b=2", ParseEmptyRestriction, 65 + 4, 1, -1},
+	}
+
+	for _, c := range cases {
+		_, err := FromString(c.input)
+		assert.ErrorIs(t, err, ErrInvalidRune, c.input)
+
+		var pe *ParseError
+		if !assert.True(t, errors.As(err, &pe), c.input) {
+			continue
+		}
+		assert.Equal(t, c.kind, pe.Kind, c.input)
+		assert.Equal(t, c.offset, pe.Offset, c.input)
+		assert.Equal(t, c.restriction, pe.Restriction, c.input)
+		assert.Equal(t, c.alternative, pe.Alternative, c.input)
+	}
+}
+
+func TestParseErrorBadField(t *testing.T) {
+	_, err := MakeAlternative("me.thod", "=", "x", false)
+
+	var pe *ParseError
+	assert.True(t, errors.As(err, &pe))
+	assert.Equal(t, ParseBadField, pe.Kind)
+	assert.Equal(t, "me.thod", pe.Token)
+}
+
+func TestParseErrorBase64(t *testing.T) {
+	var pe *ParseError
+
+	_, err := FromBase64("N0cI__dx!dWXnsh11WzSKG9tPPfsMXo7JWMqqyjsN7s")
+	assert.True(t, errors.As(err, &pe))
+	assert.Equal(t, ParseBadBase64, pe.Kind)
+	assert.Equal(t, 8, pe.Offset)
+
+	_, err = FromBase64("N0cI")
+	assert.True(t, errors.As(err, &pe))
+	assert.Equal(t, ParseBadFormat, pe.Kind)
+
+	// Restriction errors are preserved and point into decoded data
+	authcode, _ := FromString(zeroAuthCode + ":")
+	data := append(authcode.GetAuthCode(), []byte("a=1&method@x")...)
+	_, err = FromBase64(strings.TrimRight(base64.URLEncoding.EncodeToString(data), "="))
+	assert.True(t, errors.As(err, &pe))
+	assert.Equal(t, ParseUnknownOperator, pe.Kind)
+	assert.Equal(t, 32+10, pe.Offset)
+	assert.Equal(t, 1, pe.Restriction)
+	assert.Contains(t, err.Error(), "unknown operator")
+}
+
+func TestStrictMode(t *testing.T) {
+	strict := ParseOptions{Strict: true}
+
+	// Unicode punctuation is part of field name in reference implementation
+	restrictions, err := MakeRestrictionsFromStringWithOptions("a¿b=1", strict)
+	assert.NoError(t, err)
+	assert.Equal(t, "a¿b", restrictions[0].Alternatives[0].Field)
+	assert.Equal(t, "=", restrictions[0].Alternatives[0].Cond)
+
+	// ...while lenient mode treats it as (unknown) operator
+	_, err = MakeRestrictionsFromString("a¿b=1")
+	var pe *ParseError
+	assert.True(t, errors.As(err, &pe))
+	assert.Equal(t, ParseUnknownOperator, pe.Kind)
+
+	// Strict mode does not trim whitespace
+	restrictions, err = MakeRestrictionsFromStringWithOptions(" a=1 ", strict)
+	assert.NoError(t, err)
+	assert.Equal(t, " a", restrictions[0].Alternatives[0].Field)
+	assert.Equal(t, "1 ", restrictions[0].Alternatives[0].Value)
+
+	restrictions, err = MakeRestrictionsFromString(" a=1 ")
+	assert.NoError(t, err)
+	assert.Equal(t, "a", restrictions[0].Alternatives[0].Field)
+
+	// Invalid UTF-8 is rejected only in strict mode
+	_, err = FromStringWithOptions(zeroAuthCode+":a=1&b=\xff", strict)
+	assert.True(t, errors.As(err, &pe))
+	assert.Equal(t, ParseBadUTF8, pe.Kind)
+	assert.Equal(t, 65+6, pe.Offset)
+
+	_, err = FromString(zeroAuthCode + ":a=1&b=\xff")
+	assert.NoError(t, err)
+
+	authcode, _ := FromString(zeroAuthCode + ":")
+	data := append(authcode.GetAuthCode(), []byte("a=\xfe")...)
+	encoded := base64.URLEncoding.EncodeToString(data)
+
+	_, err = FromBase64WithOptions(encoded, strict)
+	assert.ErrorIs(t, err, ErrInvalidRune)
+	_, err = FromBase64(encoded)
+	assert.NoError(t, err)
+
+	// Things that reference also rejects
+	for _, str := range []string{"a", "a@1", "a=1&=2", `a=1\`, "&"} {
+		_, err = MakeRestrictionsFromStringWithOptions(str, strict)
+		assert.ErrorIs(t, err, ErrInvalidRune, str)
+	}
+}
+
+func TestParseFormats(t *testing.T) {
+	// Data length is not a multiple of 3 so padded and unpadded forms differ
+	expected := MustGetFromString(zeroAuthCode + ":")
+	url := expected.ToBase64()
+	std := base64.StdEncoding.EncodeToString(append(expected.GetAuthCode(), []byte(strings.SplitN(expected.String(), ":", 2)[1])...))
+
+	for input, format := range map[string]Format{
+		expected.String():                FormatHex,
+		url:                              FormatBase64URL,
+		expected.ToBase64Internal(false): FormatBase64URLPadded,
+		strings.TrimRight(std, "="):      FormatBase64Std,
+		std:                              FormatBase64StdPadded,
+		"  " + url + "\n":                FormatBase64URL,
+		`"` + url + `"`:                  FormatBase64URL,
+		` '` + std + `' `:                FormatBase64StdPadded,
+		"`" + expected.String() + "`":    FormatHex,
+	} {
+		r, detected, err := Parse(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, format, detected, input)
+		if r != nil {
+			assert.Equal(t, expected.String(), r.String(), input)
+		}
+	}
+
+	_, format, err := Parse("ab+c-d")
+	assert.ErrorIs(t, err, ErrInvalidRune)
+	assert.Equal(t, FormatUnknown, format)
+	assert.Equal(t, "unknown", format.String())
+}
+
+func FuzzParse(f *testing.F) {
+	for _, seed := range []string{"", `""`, "'", zeroAuthCode + ":", zeroAuthCode + ":a=1|b\\", "N0cI__dxndWXnsh11WzSKG9tPPfsMXo7JWMqqyjsN7s", "N0cI+/dx====", "=", " \" \" "} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		r, _, err := Parse(s)
+		if err == nil && r == nil {
+			t.Fatal("nil rune without error")
+		}
+		if err != nil && !errors.Is(err, ErrInvalidRune) {
+			t.Fatalf("unexpected error %v", err)
+		}
+	})
+}
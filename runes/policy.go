@@ -0,0 +1,454 @@
+package runes
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Policy expressions are arbitrary boolean expressions over rune alternatives, e.g.
+//
+//	(method=pay AND amount<1000) OR method^list
+//
+// Atoms use the same field/operator syntax as rune restrictions, a value is either a bare word (ending at
+// whitespace or parenthesis) or a double-quoted Go string. Operators are AND, OR, NOT (case insensitive, && and ||
+// are also accepted) and parentheses. "field!" means the field is missing.
+//
+// CompilePolicy converts the expression to conjunctive normal form, which is exactly what a rune is (AND of ORs).
+// Negations are mapped to the complementary operators:
+//
+//	NOT f=v  ->  f/v OR f!          NOT f/v  ->  f=v OR f!
+//	NOT f!   ->  f~ (present)       NOT f{v  ->  f=v OR f}v OR f!
+//	                                NOT f}v  ->  f=v OR f{v OR f!
+//
+// Numeric comparisons cannot be negated since a rune has no "greater or equal" and no way to match a
+// non-numeric value (NOT f<5 must accept "abc" and 5 but deny 4.5), prefix/suffix/contains conditions can only
+// be negated when their value is empty and comments cannot be negated at all. ErrNotRepresentable is returned
+// in those cases.
+
+var (
+	// ErrInvalidPolicy represents an error where policy expression could not be parsed
+	ErrInvalidPolicy = errors.New("invalid policy")
+	// ErrNotRepresentable represents an error where policy cannot be represented exactly as a rune
+	ErrNotRepresentable = errors.New("policy cannot be represented exactly")
+)
+
+// MaxPolicyClauses is the maximum number of restrictions CompilePolicy will produce
+var MaxPolicyClauses = 256
+
+// Expr is a node of policy expression
+type Expr interface {
+	// String returns a string representation
+	String() string
+	// Evaluate evaluates the expression directly (without conversion to rune)
+	Evaluate(vals map[string]any) bool
+}
+
+// AtomExpr is a single condition
+type AtomExpr struct {
+	Alternative Alternative
+}
+
+// NotExpr negates the expression
+type NotExpr struct {
+	Expr Expr
+}
+
+// AndExpr is satisfied when all expressions are
+type AndExpr struct {
+	Exprs []Expr
+}
+
+// OrExpr is satisfied when any of expressions is
+type OrExpr struct {
+	Exprs []Expr
+}
+
+// String returns a string representation
+func (e *AtomExpr) String() string {
+	return e.Alternative.String()
+}
+
+// Evaluate evaluates the expression
+func (e *AtomExpr) Evaluate(vals map[string]any) bool {
+	ok, _ := e.Alternative.Evaluate(vals)
+	return ok
+}
+
+// String returns a string representation
+func (e *NotExpr) String() string {
+	return "NOT " + e.Expr.String()
+}
+
+// Evaluate evaluates the expression
+func (e *NotExpr) Evaluate(vals map[string]any) bool {
+	return !e.Expr.Evaluate(vals)
+}
+
+func joinExprs(exprs []Expr, op string) string {
+	str := make([]string, 0, len(exprs))
+	for _, one := range exprs {
+		str = append(str, one.String())
+	}
+
+	return "(" + strings.Join(str, " "+op+" ") + ")"
+}
+
+// String returns a string representation
+func (e *AndExpr) String() string {
+	return joinExprs(e.Exprs, "AND")
+}
+
+// Evaluate evaluates the expression
+func (e *AndExpr) Evaluate(vals map[string]any) bool {
+	for _, one := range e.Exprs {
+		if !one.Evaluate(vals) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// String returns a string representation
+func (e *OrExpr) String() string {
+	return joinExprs(e.Exprs, "OR")
+}
+
+// Evaluate evaluates the expression
+func (e *OrExpr) Evaluate(vals map[string]any) bool {
+	for _, one := range e.Exprs {
+		if one.Evaluate(vals) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type policyParser struct {
+	str string
+	pos int
+}
+
+func (p *policyParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w at offset %d: %s", ErrInvalidPolicy, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *policyParser) skipSpace() {
+	for p.pos < len(p.str) {
+		r, size := utf8.DecodeRuneInString(p.str[p.pos:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		p.pos += size
+	}
+}
+
+// keyword consumes one of keywords (if present) and returns it
+func (p *policyParser) keyword(keywords ...string) string {
+	p.skipSpace()
+	rest := p.str[p.pos:]
+
+	for _, kw := range keywords {
+		if len(rest) < len(kw) || !strings.EqualFold(rest[:len(kw)], kw) {
+			continue
+		}
+		if unicode.IsLetter(rune(kw[0])) && len(rest) > len(kw) {
+			// Word keyword must not be followed by a part of a field name or an operator
+			next, _ := utf8.DecodeRuneInString(rest[len(kw):])
+			if !unicode.IsSpace(next) && next != '(' && next != ')' {
+				continue
+			}
+		}
+		p.pos += len(kw)
+		return strings.ToUpper(kw)
+	}
+
+	return ""
+}
+
+func (p *policyParser) parseOr() (Expr, error) {
+	exprs := make([]Expr, 0)
+	for {
+		e, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e)
+
+		if p.keyword("OR", "||") == "" {
+			break
+		}
+	}
+
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return &OrExpr{Exprs: exprs}, nil
+}
+
+func (p *policyParser) parseAnd() (Expr, error) {
+	exprs := make([]Expr, 0)
+	for {
+		e, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e)
+
+		if p.keyword("AND", "&&") == "" {
+			break
+		}
+	}
+
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return &AndExpr{Exprs: exprs}, nil
+}
+
+func (p *policyParser) parseNot() (Expr, error) {
+	if p.keyword("NOT") != "" {
+		e, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &NotExpr{Expr: e}, nil
+	}
+
+	p.skipSpace()
+	if p.pos < len(p.str) && p.str[p.pos] == '(' {
+		p.pos++
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.pos >= len(p.str) || p.str[p.pos] != ')' {
+			return nil, p.errorf("expected ')'")
+		}
+		p.pos++
+		return e, nil
+	}
+
+	return p.parseAtom()
+}
+
+func (p *policyParser) parseAtom() (Expr, error) {
+	start := p.pos
+	cond := ""
+	for i, r := range p.str[start:] {
+		if unicode.IsSpace(r) || r == '(' || r == ')' {
+			break
+		}
+		if isPunct(r) {
+			cond = string(r)
+			p.pos = start + i + len(cond)
+			break
+		}
+	}
+
+	if cond == "" {
+		return nil, p.errorf("expected condition")
+	}
+	field := p.str[start : p.pos-len(cond)]
+
+	value := ""
+	if p.pos < len(p.str) && p.str[p.pos] == '"' {
+		quoted, err := strconv.QuotedPrefix(p.str[p.pos:])
+		if err != nil {
+			return nil, p.errorf("bad quoted value")
+		}
+		value, _ = strconv.Unquote(quoted)
+		p.pos += len(quoted)
+	} else {
+		end := p.pos
+		for end < len(p.str) {
+			r, size := utf8.DecodeRuneInString(p.str[end:])
+			if unicode.IsSpace(r) || r == '(' || r == ')' {
+				break
+			}
+			end += size
+		}
+		value = p.str[p.pos:end]
+		p.pos = end
+	}
+
+	if field == "" {
+		return nil, p.errorf("empty field name")
+	}
+
+	alt, err := MakeAlternative(field, cond, value, false)
+	if err != nil {
+		return nil, fmt.Errorf("%w at offset %d: %v", ErrInvalidPolicy, start, err)
+	}
+
+	return &AtomExpr{Alternative: *alt}, nil
+}
+
+// ParsePolicy parses a policy expression
+func ParsePolicy(str string) (Expr, error) {
+	p := &policyParser{str: str}
+
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	if p.pos < len(p.str) {
+		return nil, p.errorf("unexpected %q", p.str[p.pos:])
+	}
+
+	return e, nil
+}
+
+type clause []Alternative
+
+func mustAlternative(field, cond string, value any) Alternative {
+	alt, err := MakeAlternative(field, cond, value, false)
+	if err != nil {
+		panic(err)
+	}
+	return *alt
+}
+
+// complement returns alternatives (joined by OR) that are satisfied exactly when alt is not
+func complement(alt Alternative) (clause, error) {
+	f := alt.Field
+	value := fmt.Sprintf("%v", alt.Value)
+	missing := mustAlternative(f, "!", "")
+
+	switch alt.Cond {
+	case "=":
+		return clause{mustAlternative(f, "/", value), missing}, nil
+	case "/":
+		return clause{mustAlternative(f, "=", value), missing}, nil
+	case "!":
+		return clause{mustAlternative(f, "~", "")}, nil
+	case "{":
+		return clause{mustAlternative(f, "=", value), mustAlternative(f, "}", value), missing}, nil
+	case "}":
+		return clause{mustAlternative(f, "=", value), mustAlternative(f, "{", value), missing}, nil
+	case "^", "$", "~":
+		if value == "" {
+			// Empty prefix, suffix or substring just means present
+			return clause{missing}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: NOT %s", ErrNotRepresentable, alt.String())
+}
+
+// toCNF returns clauses (joined by AND) equivalent to e (or its negation)
+func toCNF(e Expr, negate bool) ([]clause, error) {
+	switch x := e.(type) {
+	case *AtomExpr:
+		if !negate {
+			return []clause{{x.Alternative}}, nil
+		}
+		c, err := complement(x.Alternative)
+		if err != nil {
+			return nil, err
+		}
+		return []clause{c}, nil
+	case *NotExpr:
+		return toCNF(x.Expr, !negate)
+	case *AndExpr, *OrExpr:
+		var (
+			exprs []Expr
+			and   bool
+		)
+		if a, ok := x.(*AndExpr); ok {
+			exprs, and = a.Exprs, true
+		} else {
+			exprs = x.(*OrExpr).Exprs
+		}
+		// De Morgan
+		if negate {
+			and = !and
+		}
+
+		if and {
+			ret := make([]clause, 0)
+			for _, one := range exprs {
+				cnf, err := toCNF(one, negate)
+				if err != nil {
+					return nil, err
+				}
+				ret = append(ret, cnf...)
+				if len(ret) > MaxPolicyClauses {
+					return nil, fmt.Errorf("%w: more than %d restrictions", ErrNotRepresentable, MaxPolicyClauses)
+				}
+			}
+			return ret, nil
+		}
+
+		// Distribute OR over AND
+		ret := []clause{{}}
+		for _, one := range exprs {
+			cnf, err := toCNF(one, negate)
+			if err != nil {
+				return nil, err
+			}
+			if len(ret)*len(cnf) > MaxPolicyClauses {
+				return nil, fmt.Errorf("%w: more than %d restrictions", ErrNotRepresentable, MaxPolicyClauses)
+			}
+			product := make([]clause, 0, len(ret)*len(cnf))
+			for _, a := range ret {
+				for _, b := range cnf {
+					product = append(product, append(append(clause{}, a...), b...))
+				}
+			}
+			ret = product
+		}
+		return ret, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown expression %T", ErrInvalidPolicy, e)
+	}
+}
+
+// ToRestrictions converts policy expression to restrictions (conjunctive normal form)
+func ToRestrictions(e Expr) ([]Restriction, error) {
+	cnf, err := toCNF(e, false)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]Restriction, 0, len(cnf))
+	seenRestrictions := make(map[string]bool)
+	for _, c := range cnf {
+		alternatives := make([]Alternative, 0, len(c))
+		seen := make(map[string]bool)
+		for _, alt := range c {
+			if seen[alt.String()] {
+				continue
+			}
+			seen[alt.String()] = true
+			alternatives = append(alternatives, alt)
+		}
+
+		r := Restriction{Alternatives: alternatives}
+		if seenRestrictions[r.String()] {
+			continue
+		}
+		seenRestrictions[r.String()] = true
+		ret = append(ret, r)
+	}
+
+	return ret, nil
+}
+
+// CompilePolicy parses policy expression and converts it to restrictions
+func CompilePolicy(str string) ([]Restriction, error) {
+	e, err := ParsePolicy(str)
+	if err != nil {
+		return nil, err
+	}
+
+	return ToRestrictions(e)
+}
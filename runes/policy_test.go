@@ -0,0 +1,142 @@
+package runes
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func evaluateRestrictions(restrictions []Restriction, vals map[string]any) bool {
+	for _, r := range restrictions {
+		ok, _ := r.Evaluate(vals)
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// policyValues returns all combinations of values for method (string) and amount (integer) fields
+func policyValues() []map[string]any {
+	ret := make([]map[string]any, 0)
+	for _, method := range []any{nil, "pay", "listpeers", "list", "getinfo", "a", "z"} {
+		for _, amount := range []any{nil, -1, 0, 999, 999.5, "999.5", 1000, 1000.0, 1001, 5000, "abc", ""} {
+			vals := make(map[string]any)
+			if method != nil {
+				vals["method"] = method
+			}
+			if amount != nil {
+				vals["amount"] = amount
+			}
+			ret = append(ret, vals)
+		}
+	}
+	return ret
+}
+
+func TestCompilePolicy(t *testing.T) {
+	for _, policy := range []string{
+		"(method=pay AND amount<1000) OR method^list",
+		"method=pay and amount<1000",
+		"NOT method=pay",
+		"NOT (method=pay OR method{list)",
+		"NOT (method=pay AND amount!) OR amount>1000",
+		"NOT method=pay OR amount<1000 AND amount>500",
+		"NOT method! AND NOT amount!",
+		"NOT NOT method=pay",
+		"method{list OR NOT method}pay",
+		"NOT method{list && NOT method}pay",
+		"(method=pay OR method=getinfo) AND (amount<1000 OR amount>4000) OR method^list",
+		`method="pay" || method="a b"`,
+		"NOT method^",
+		"method#comment AND method/pay",
+	} {
+		e, err := ParsePolicy(policy)
+		if !assert.NoError(t, err, policy) {
+			continue
+		}
+		restrictions, err := ToRestrictions(e)
+		if !assert.NoError(t, err, policy) {
+			continue
+		}
+
+		// Compiled restrictions must behave exactly like the expression
+		var strs []string
+		for _, r := range restrictions {
+			strs = append(strs, r.String())
+		}
+		for _, vals := range policyValues() {
+			assert.Equal(t, e.Evaluate(vals), evaluateRestrictions(restrictions, vals), "%s (%v) with %v", policy, strs, vals)
+		}
+	}
+}
+
+func TestCompilePolicyShape(t *testing.T) {
+	restrictions, err := CompilePolicy("(method=pay AND amount<1000) OR method^list")
+	assert.NoError(t, err)
+	assert.Equal(t, MustMakeRestrictionsFromString("method=pay|method^list&amount<1000|method^list"), restrictions)
+
+	restrictions, err = CompilePolicy("NOT method=pay")
+	assert.NoError(t, err)
+	assert.Equal(t, MustMakeRestrictionsFromString("method/pay|method!"), restrictions)
+
+	restrictions, err = CompilePolicy("NOT method!")
+	assert.NoError(t, err)
+	assert.Equal(t, MustMakeRestrictionsFromString("method~"), restrictions)
+
+	// Duplicates are removed
+	restrictions, err = CompilePolicy("method=pay AND (method=pay OR method=pay)")
+	assert.NoError(t, err)
+	assert.Equal(t, MustMakeRestrictionsFromString("method=pay"), restrictions)
+}
+
+func TestCompilePolicyUnicode(t *testing.T) {
+	alt := func(field, value string) Alternative {
+		return Alternative{Field: field, Cond: "=", Value: value}
+	}
+	for policy, expected := range map[string][]Restriction{
+		"name=à":                           {{Alternatives: []Alternative{alt("name", "à")}}},
+		"name=ok AND x=à":                  {{Alternatives: []Alternative{alt("name", "ok")}}, {Alternatives: []Alternative{alt("x", "à")}}},
+		"name=😀 OR name=x😀y":               {{Alternatives: []Alternative{alt("name", "😀"), alt("name", "x😀y")}}},
+		"name=a\u00a0AND\u0085x=1":         {{Alternatives: []Alternative{alt("name", "a")}}, {Alternatives: []Alternative{alt("x", "1")}}},
+		`name="a\u00a0b" OR name="\u0085"`: {{Alternatives: []Alternative{alt("name", "a\u00a0b"), alt("name", "\u0085")}}},
+		"name=\u00e0\u0085":                {{Alternatives: []Alternative{alt("name", "à")}}},
+	} {
+		restrictions, err := CompilePolicy(policy)
+		assert.NoError(t, err, policy)
+		assert.Equal(t, expected, restrictions, policy)
+	}
+}
+
+func TestCompilePolicyNumericNegation(t *testing.T) {
+	// NOT amount<1000 must accept 1000, "abc" and missing amount but deny 999.5, no rune does exactly that
+	for _, policy := range []string{"NOT amount<1000", "NOT amount>1000", "NOT (amount<1000 OR method=pay)", "NOT amount<1.5"} {
+		_, err := CompilePolicy(policy)
+		assert.ErrorIs(t, err, ErrNotRepresentable, policy)
+	}
+
+	restrictions, err := CompilePolicy("amount>999 OR amount!")
+	assert.NoError(t, err)
+	assert.Equal(t, MustMakeRestrictionsFromString("amount>999|amount!"), restrictions)
+}
+
+func TestCompilePolicyErrors(t *testing.T) {
+	for _, policy := range []string{"", "(method=pay", "method=pay)", "method", "=1", "method@x", "method=pay AND", `method="unterminated`} {
+		_, err := CompilePolicy(policy)
+		assert.ErrorIs(t, err, ErrInvalidPolicy, policy)
+	}
+
+	for _, policy := range []string{"NOT method^list", "NOT method$x", "NOT method~x", "NOT method#x", "NOT amount<1000", "NOT amount>1000", "NOT (method=pay OR amount>1000)", "NOT amount<1.5"} {
+		_, err := CompilePolicy(policy)
+		assert.ErrorIs(t, err, ErrNotRepresentable, policy)
+	}
+
+	// Exponential blowup is refused
+	policy := "a=0 AND b=0"
+	for i := 1; i < 10; i++ {
+		policy = fmt.Sprintf("(%s) OR (a=%d AND b=%d)", policy, i, i)
+	}
+	_, err := CompilePolicy(policy)
+	assert.ErrorIs(t, err, ErrNotRepresentable)
+}
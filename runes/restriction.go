@@ -1,8 +1,12 @@
 package runes
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 // Restriction struct
@@ -21,6 +25,28 @@ func MakeRestriction(alternatives []Alternative) (*Restriction, error) {
 	}, nil
 }
 
+// checkRestriction validates alternatives the same way as parser does
+func checkRestriction(alternatives []Alternative, allowIDField bool) (*Restriction, error) {
+	for i, alt := range alternatives {
+		_, err := MakeAlternative(alt.Field, alt.Cond, alt.Value, allowIDField && i == 0)
+		if err != nil {
+			var pe *ParseError
+			if errors.As(err, &pe) {
+				pe.Alternative = i
+			}
+			return nil, err
+		}
+	}
+
+	if len(alternatives) > 1 && alternatives[0].IsUniqueID() {
+		pe := newParseError(ParseMisplacedUniqueID, 0, alternatives[1].String(), "unique_id field cannot have alternatives")
+		pe.Alternative = 1
+		return nil, pe
+	}
+
+	return MakeRestriction(alternatives)
+}
+
 // String returns a string representation
 func (r *Restriction) String() string {
 	str := make([]string, 0)
@@ -33,36 +59,64 @@ func (r *Restriction) String() string {
 
 // Evaluate evaluates the restriction
 func (r *Restriction) Evaluate(vals map[string]any) (bool, string) {
-	reasons := make([]string, 0)
+	result := r.EvaluateResult(vals)
+	return result.Outcome == OutcomePass, result.Reason()
+}
+
+// EvaluateResult evaluates the restriction and records the details of every evaluated alternative
+func (r *Restriction) EvaluateResult(vals map[string]any) RestrictionResult {
+	return r.evaluateResult(contextLookup(context.Background(), MapValues(vals)), EvalOptions{})
+}
+
+func (r *Restriction) evaluateResult(lookup lookupFunc, opts EvalOptions) RestrictionResult {
+	result := RestrictionResult{Restriction: r.String(), Outcome: OutcomeFail, Alternatives: make([]AlternativeResult, 0, len(r.Alternatives))}
 	for _, one := range r.Alternatives {
-		b, s := one.Evaluate(vals)
-		if !b {
-			reasons = append(reasons, s)
-		} else {
-			return true, ""
+		alt := one.evaluateResult(lookup, opts)
+		result.Alternatives = append(result.Alternatives, alt)
+		if alt.Outcome == OutcomePass || alt.Outcome == OutcomeError {
+			result.Outcome = alt.Outcome
+			result.err = alt.err
+			break
 		}
 	}
 
-	return false, strings.Join(reasons, " AND ")
+	return result
 }
 
-// MakeRestrictionFromString returns a new restriction from a string
+// MakeRestrictionFromString returns a new restriction from a string (surrounding whitespace is ignored)
 func MakeRestrictionFromString(str string, allowIDField bool) (*Restriction, string, error) {
+	leading := len(str) - len(strings.TrimLeftFunc(str, unicode.IsSpace))
+
+	ret, rest, err := parseRestriction(strings.TrimSpace(str), allowIDField, ParseOptions{})
+	if err != nil {
+		return nil, "", shiftParseError(err, leading)
+	}
+
+	return ret, rest, nil
+}
+
+// parseRestriction parses one restriction exactly as encoded in a rune
+func parseRestriction(str string, allowIDField bool, opts ParseOptions) (*Restriction, string, error) {
 
 	alternatives := make([]Alternative, 0)
 
-	s := strings.TrimSpace(str)
+	s := str
 	allowID := allowIDField
 	afterRestriction := ""
 
 	for {
+		start := len(str) - len(s)
 		if strings.HasPrefix(s, "&") {
 			afterRestriction = s[1:]
 			break
 		}
-		alt, rest, err := MakeAlternativeFromString(s, allowID)
+		alt, rest, err := parseAlternative(s, allowID, opts)
 		if err != nil {
-			return nil, "", err
+			var pe *ParseError
+			if errors.As(err, &pe) {
+				pe.Alternative = len(alternatives)
+			}
+			return nil, "", shiftParseError(err, start)
 		}
 
 		alternatives = append(alternatives, *alt)
@@ -75,7 +129,11 @@ func MakeRestrictionFromString(str string, allowIDField bool) (*Restriction, str
 	}
 
 	if len(alternatives) > 1 && alternatives[0].IsUniqueID() {
-		return nil, "", fmt.Errorf("unique_id field cannot have alternatives")
+		return nil, "", newParseError(ParseMisplacedUniqueID, 0, alternatives[0].String(), "unique_id field cannot have alternatives")
+	}
+
+	if len(alternatives) < 1 {
+		return nil, "", newParseError(ParseEmptyRestriction, 0, "", "restriction must have some alternative")
 	}
 
 	ret, err := MakeRestriction(alternatives)
@@ -108,8 +166,42 @@ func UniqueID(uniqueID any, version any) (*Restriction, error) {
 		}}, nil
 }
 
-// MakeRestrictionsFromString creates restrictionn from string representation
+// MakeRestrictionsFromString creates restrictionn from string representation (surrounding whitespace is ignored)
 func MakeRestrictionsFromString(str string) ([]Restriction, error) {
+	return MakeRestrictionsFromStringWithOptions(str, ParseOptions{})
+}
+
+// MakeRestrictionsFromStringWithOptions creates restrictions from string representation using options.
+// In lenient mode surrounding whitespace is ignored, strict mode takes the string verbatim.
+func MakeRestrictionsFromStringWithOptions(str string, opts ParseOptions) ([]Restriction, error) {
+	if opts.Strict {
+		return parseRestrictions(str, opts)
+	}
+
+	leading := len(str) - len(strings.TrimLeftFunc(str, unicode.IsSpace))
+
+	ret, err := parseRestrictions(strings.TrimSpace(str), opts)
+	if err != nil {
+		return nil, shiftParseError(err, leading)
+	}
+
+	return ret, nil
+}
+
+// parseRestrictions parses restrictions exactly as encoded in a rune
+func parseRestrictions(str string, opts ParseOptions) ([]Restriction, error) {
+	if opts.Strict && !utf8.ValidString(str) {
+		offset := 0
+		for offset < len(str) {
+			r, size := utf8.DecodeRuneInString(str[offset:])
+			if r == utf8.RuneError && size == 1 {
+				break
+			}
+			offset += size
+		}
+		return nil, newParseError(ParseBadUTF8, offset, str[offset:offset+1], "restrictions are not valid UTF-8")
+	}
+
 	var err error
 	rest := str
 	restrictions := make([]Restriction, 0)
@@ -117,10 +209,15 @@ func MakeRestrictionsFromString(str string) ([]Restriction, error) {
 	var restriction *Restriction
 	for len(rest) > 0 {
 		allowIDField := len(restrictions) == 0
+		start := len(str) - len(rest)
 
-		restriction, rest, err = MakeRestrictionFromString(rest, allowIDField)
+		restriction, rest, err = parseRestriction(rest, allowIDField, opts)
 		if err != nil {
-			return nil, err
+			var pe *ParseError
+			if errors.As(err, &pe) {
+				pe.Restriction = len(restrictions)
+			}
+			return nil, shiftParseError(err, start)
 		}
 
 		restrictions = append(restrictions, *restriction)
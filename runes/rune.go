@@ -2,6 +2,7 @@ package runes
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/hex"
 	"errors"
@@ -110,16 +111,70 @@ func FromAuthCode(authcode []byte, restrictions []Restriction) (*Rune, error) {
 	return ret, nil
 }
 
+// FromMidState creates a new rune from a (deserialized) midstate so further restrictions can be added
+func FromMidState(state *MidState, restrictions []Restriction) (*Rune, error) {
+	if state == nil {
+		return nil, fmt.Errorf("nil midstate %w", ErrInvalidRune)
+	}
+
+	ret := &Rune{
+		Sha256:       NewSha256(),
+		Restrictions: append(make([]Restriction, 0, len(restrictions)), restrictions...),
+	}
+
+	err := ret.Sha256.SetMidState(state)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
 // Evaluate evaluates the rune
 func (r *Rune) Evaluate(vals map[string]any) (bool, string) {
-	for _, one := range r.Restrictions {
-		ok, msg := one.Evaluate(vals)
-		if !ok {
-			return false, msg
+	result := r.EvaluateResult(vals)
+	return result.OK(), result.Reason()
+}
+
+// EvaluateResult evaluates the rune and records the details, restrictions after the first failing one are skipped
+func (r *Rune) EvaluateResult(vals map[string]any) *EvalResult {
+	result, _ := r.EvaluateContext(context.Background(), MapValues(vals), EvalOptions{})
+	return result
+}
+
+// EvaluateWithOptions evaluates the rune like EvaluateResult, in strict mode type mismatches are returned as
+// errors (result has OutcomeError) instead of failing restrictions
+func (r *Rune) EvaluateWithOptions(vals map[string]any, opts EvalOptions) (*EvalResult, error) {
+	return r.EvaluateContext(context.Background(), MapValues(vals), opts)
+}
+
+// CheckWithOptions checks rune like EvaluateWithOptions, it returns *EvalError when rune is denied
+func (r *Rune) CheckWithOptions(vals map[string]any, opts EvalOptions) error {
+	return r.CheckContext(context.Background(), MapValues(vals), opts)
+}
+
+func (r *Rune) evaluateResult(lookup lookupFunc, opts EvalOptions) *EvalResult {
+	return evaluateAll(r.Restrictions, lookup, opts)
+}
+
+// evaluateAll evaluates restrictions in order, restrictions after the first failing one are skipped
+func evaluateAll(restrictions []Restriction, lookup lookupFunc, opts EvalOptions) *EvalResult {
+	result := &EvalResult{Outcome: OutcomePass, Restrictions: make([]RestrictionResult, 0, len(restrictions))}
+	for _, one := range restrictions {
+		if result.Outcome != OutcomePass {
+			result.Restrictions = append(result.Restrictions, RestrictionResult{Restriction: one.String(), Outcome: OutcomeSkipped})
+			continue
+		}
+
+		restriction := one.evaluateResult(lookup, opts)
+		result.Restrictions = append(result.Restrictions, restriction)
+		if restriction.Outcome != OutcomePass {
+			result.Outcome = restriction.Outcome
+			result.Err = restriction.err
 		}
 	}
 
-	return true, ""
+	return result
 }
 
 // String returns a string representation of rune
@@ -168,28 +223,31 @@ func MustGetFromString(str string) Rune {
 
 // FromString returns a new rune from string representation
 func FromString(str string) (*Rune, error) {
+	return FromStringWithOptions(str, ParseOptions{})
+}
+
+// FromStringWithOptions returns a new rune from string representation using options
+func FromStringWithOptions(str string, opts ParseOptions) (*Rune, error) {
 	if len(str) < 65 || str[64] != ':' {
-		return nil, fmt.Errorf("rune strings must start with 64 hex digits then '-' %w", ErrInvalidRune)
+		offset := len(str)
+		if offset > 64 {
+			offset = 64
+		}
+		return nil, newParseError(ParseBadFormat, offset, "", "rune strings must start with 64 hex digits then ':'")
 	}
 
 	authcode, err := hex.DecodeString(str[0:64])
 	if err != nil {
-		return nil, fmt.Errorf("%w", ErrInvalidRune)
-	}
-
-	rest := str[65:]
-	restrictions := make([]Restriction, 0)
-
-	var restriction *Restriction
-	for len(rest) > 0 {
-		allowIDField := len(restrictions) == 0
-
-		restriction, rest, err = MakeRestrictionFromString(rest, allowIDField)
-		if err != nil {
-			return nil, fmt.Errorf("%w", ErrInvalidRune)
+		offset := 0
+		for offset < 64 && strings.ContainsRune("0123456789abcdefABCDEF", rune(str[offset])) {
+			offset++
 		}
+		return nil, newParseError(ParseBadHex, offset, str[offset:offset+1], err.Error())
+	}
 
-		restrictions = append(restrictions, *restriction)
+	restrictions, err := parseRestrictions(str[65:], opts)
+	if err != nil {
+		return nil, shiftParseError(err, 65)
 	}
 
 	return FromAuthCode(authcode, restrictions)
@@ -207,19 +265,35 @@ func MustGetFromBase64(str string) Rune {
 
 // FromBase64 returns a new rune from base64 encoded string representation
 func FromBase64(str string) (*Rune, error) {
+	return FromBase64WithOptions(str, ParseOptions{})
+}
+
+// FromBase64WithOptions returns a new rune from base64 encoded string representation using options
+func FromBase64WithOptions(str string, opts ParseOptions) (*Rune, error) {
 	str = strings.TrimRight(str, "=")
 	addendum := strings.Repeat("=", (4-(len(str)%4))%4)
 
 	data, err := base64.URLEncoding.DecodeString(str + addendum)
 	if err != nil {
-		return nil, fmt.Errorf("%w", ErrInvalidRune)
+		offset := 0
+		var corrupt base64.CorruptInputError
+		if errors.As(err, &corrupt) {
+			offset = int(corrupt)
+		}
+		return nil, newParseError(ParseBadBase64, offset, "", err.Error())
 	}
 
 	if len(data) < 32 {
-		return nil, fmt.Errorf("wrong data %w", ErrInvalidRune)
+		return nil, newParseError(ParseBadFormat, len(data), "", "wrong data")
 	}
 
-	return FromString(hex.EncodeToString(data[:32]) + ":" + string(data[32:]))
+	ret, err := FromStringWithOptions(hex.EncodeToString(data[:32])+":"+string(data[32:]), opts)
+	if err != nil {
+		// Offsets should refer to decoded data
+		return nil, shiftParseError(err, 32-65)
+	}
+
+	return ret, nil
 }
 
 // GetRestricted obtains a restricted rune
@@ -247,14 +321,9 @@ func (r *Rune) MustGetRestrictedFromString(str string) Rune {
 	return *ret
 }
 
-// Check checks rune
+// Check checks rune, it returns *EvalError when rune is denied
 func (r *Rune) Check(vals map[string]any) error {
-	ok, msg := r.Evaluate(vals)
-	if ok {
-		return nil
-	}
-
-	return fmt.Errorf(msg)
+	return r.CheckContext(context.Background(), MapValues(vals), EvalOptions{})
 }
 
 func (r *Rune) getID() string {
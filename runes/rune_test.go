@@ -56,3 +56,114 @@ func TestUnparsable(t *testing.T) {
 	_, err = FromString("1edf4068e2b0b1e4e075e66751c2d3f5c9fc4515d114f875e6dc6e3e6704efa9")
 	assert.ErrorIs(t, err, ErrInvalidRune)
 }
+
+func TestFromMidState(t *testing.T) {
+	var secret [16]byte
+	master := MustMakeMasterRune(secret[:])
+	tenant := master.MustGetRestrictedFromString("tenant=burek")
+
+	// Checkpoint is stored by one process...
+	b, err := tenant.Sha256.GetMidState().MarshalBinary()
+	assert.NoError(t, err)
+
+	// ...and resumed by another without the seed secret
+	var state MidState
+	assert.NoError(t, state.UnmarshalBinary(b))
+	resumed, err := FromMidState(&state, tenant.Restrictions)
+	assert.NoError(t, err)
+	err = resumed.AddRestriction(MustMakeRestrictionsFromString("method^list")[0])
+	assert.NoError(t, err)
+
+	expected := tenant.MustGetRestrictedFromString("method^list")
+	assert.Equal(t, expected.String(), resumed.String())
+	assert.Equal(t, true, master.IsRuneAuthorized(resumed))
+
+	_, err = FromMidState(&MidState{Len: 1}, nil)
+	assert.ErrorIs(t, err, ErrUnalignedMidState)
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	for _, seed := range []string{"", "plain", `a\b`, "a&b", "a|b", `\`, `\\&|`, "&&||", "trailing ", " leading", "x=y", "\x00\xff"} {
+		f.Add(seed, seed)
+	}
+
+	base := MustGetFromString("374708fff7719dd5979ec875d56cd2286f6d3cf7ec317a3b25632aab28ec37bb:")
+
+	f.Fuzz(func(t *testing.T, one string, two string) {
+		a, err := MakeAlternative("f", "=", one, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := MakeAlternative("g", "^", two, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		restricted, err := base.GetRestricted(Restriction{Alternatives: []Alternative{*a, *b}}, Restriction{Alternatives: []Alternative{*b}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		parsed, err := FromString(restricted.String())
+		if err != nil {
+			t.Fatalf("could not parse %q: %v", restricted.String(), err)
+		}
+		assert.Equal(t, restricted.Restrictions, parsed.Restrictions)
+		assert.Equal(t, restricted.GetAuthCode(), parsed.GetAuthCode())
+
+		decoded, err := FromBase64(restricted.ToBase64())
+		if err != nil {
+			t.Fatalf("could not parse %q: %v", restricted.ToBase64(), err)
+		}
+		assert.Equal(t, restricted.Restrictions, decoded.Restrictions)
+	})
+}
+
+func TestUTF8(t *testing.T) {
+	base := MustGetFromString("374708fff7719dd5979ec875d56cd2286f6d3cf7ec317a3b25632aab28ec37bb:")
+
+	for _, c := range []struct {
+		restrictions string
+		field        string
+		cond         string
+		value        string
+		pass         string
+		fail         string
+	}{
+		{"方法^列表", "方法", "^", "列表", "列表人", "人列表"},
+		{"emoji😀=🚀&x", "emoji😀", "=", "🚀&x", "🚀&x", "🚀"},
+		{"café$é", "café", "$", "é", "café", "café"},
+		{"naïve~日本", "naïve", "~", "日本", "こんにちは日本です", "日 本"},
+		{"x=ü|y=z", "x", "=", "ü", "ü", "u"},
+		{"cafe\u0301=e\u0301", "cafe\u0301", "=", "e\u0301", "e\u0301", "\u00e9"},
+		{"ключ{яблоко", "ключ", "{", "яблоко", "апельсин", "яблоко"},
+	} {
+		a, err := MakeAlternative(c.field, c.cond, c.value, false)
+		assert.NoError(t, err, c.restrictions)
+
+		restricted, err := base.GetRestricted(Restriction{Alternatives: []Alternative{*a}})
+		assert.NoError(t, err)
+
+		parsed, err := FromString(restricted.String())
+		assert.NoError(t, err, c.restrictions)
+		assert.Equal(t, restricted.Restrictions, parsed.Restrictions, c.restrictions)
+
+		decoded, err := FromBase64(restricted.ToBase64())
+		assert.NoError(t, err, c.restrictions)
+		assert.Equal(t, restricted.Restrictions, decoded.Restrictions, c.restrictions)
+		assert.Equal(t, restricted.String(), decoded.String(), c.restrictions)
+
+		ok, msg := decoded.Evaluate(map[string]any{c.field: c.pass})
+		assert.True(t, ok, "%s: %s", c.restrictions, msg)
+		ok, _ = decoded.Evaluate(map[string]any{c.field: c.fail})
+		assert.False(t, ok, c.restrictions)
+	}
+
+	// Parsing the string form splits on the first punctuation even after multi-byte characters
+	restrictions, err := MakeRestrictionsFromString("方法^列表|名前=太郎&時間<5")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(restrictions))
+	assert.Equal(t, Alternative{Field: "方法", Cond: "^", Value: "列表"}, restrictions[0].Alternatives[0])
+	assert.Equal(t, Alternative{Field: "名前", Cond: "=", Value: "太郎"}, restrictions[0].Alternatives[1])
+	assert.Equal(t, Alternative{Field: "時間", Cond: "<", Value: "5"}, restrictions[1].Alternatives[0])
+}
@@ -0,0 +1,429 @@
+package runes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+var (
+	// ErrUnsatisfiable represents an error where restrictions can never be satisfied
+	ErrUnsatisfiable = errors.New("restrictions are unsatisfiable")
+	// ErrSatisfiabilityUnknown represents an error where satisfiability could not be decided
+	ErrSatisfiabilityUnknown = errors.New("could not decide satisfiability")
+)
+
+// maxSatisfiableSteps limits the witness search
+const maxSatisfiableSteps = 100000
+
+// maxExactFloat is the largest magnitude where every integer is exactly representable as float64
+const maxExactFloat = 1 << 53
+
+// UnsatisfiableError explains why restrictions can never pass
+type UnsatisfiableError struct {
+	// Restrictions are the indexes of conflicting restrictions
+	Restrictions []int
+	// Reason is a human readable explanation
+	Reason string
+}
+
+// Error returns the error message
+func (e *UnsatisfiableError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrUnsatisfiable, e.Reason)
+}
+
+// Unwrap makes errors.Is(err, ErrUnsatisfiable) work
+func (e *UnsatisfiableError) Unwrap() error {
+	return ErrUnsatisfiable
+}
+
+// IssueOptions control how master rune issues new runes
+type IssueOptions struct {
+	// RequireSatisfiable refuses to issue runes whose restrictions can never pass
+	RequireSatisfiable bool
+}
+
+// Issue obtains a restricted rune honoring options
+func (r *MasterRune) Issue(opts IssueOptions, restrictions ...Restriction) (*Rune, error) {
+	ret, err := r.GetRestricted(restrictions...)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.RequireSatisfiable {
+		_, err = ret.Satisfiable()
+		// Unknown is not a reason to refuse
+		if errors.Is(err, ErrUnsatisfiable) {
+			return nil, err
+		}
+	}
+
+	return ret, nil
+}
+
+// Satisfiable checks whether the rune's restrictions can ever pass. When they can it returns a witness
+// (values that pass Evaluate), when they provably cannot it returns an *UnsatisfiableError explaining the
+// conflict. In rare cases where neither could be established ErrSatisfiabilityUnknown is returned.
+func (r *Rune) Satisfiable() (map[string]any, error) {
+	return satisfiable(r.Restrictions)
+}
+
+func satisfiable(restrictions []Restriction) (map[string]any, error) {
+	err := proveUnsatisfiable(restrictions)
+	if err != nil {
+		return nil, err
+	}
+
+	witness, ok := findWitness(restrictions, nil)
+	if ok {
+		return witness, nil
+	}
+
+	return nil, ErrSatisfiabilityUnknown
+}
+
+// neverSatisfied reports whether alternative fails for every value
+func neverSatisfied(alt Alternative) bool {
+	if alt.Cond != "<" && alt.Cond != ">" {
+		return false
+	}
+
+	num, ok := parseNumber(fmt.Sprintf("%v", alt.Value))
+	if !ok {
+		return true
+	}
+	if num.kind != numberFloat {
+		return false
+	}
+
+	return math.IsNaN(num.f) || (alt.Cond == "<" && math.IsInf(num.f, -1)) || (alt.Cond == ">" && math.IsInf(num.f, 1))
+}
+
+// unitConstraint is a restriction whose (possibly satisfiable) alternatives all refer to one field
+type unitConstraint struct {
+	index        int
+	restriction  Restriction
+	alternatives []Alternative
+}
+
+func unitConstraints(restrictions []Restriction) (map[string][]unitConstraint, error) {
+	ret := make(map[string][]unitConstraint)
+
+	for i, r := range restrictions {
+		alternatives := make([]Alternative, 0, len(r.Alternatives))
+		tautology := false
+		for _, alt := range r.Alternatives {
+			if alt.IsUniqueID() || alt.Cond == "#" {
+				tautology = true
+				break
+			}
+			if !neverSatisfied(alt) {
+				alternatives = append(alternatives, alt)
+			}
+		}
+		if tautology {
+			continue
+		}
+
+		if len(alternatives) == 0 {
+			return nil, &UnsatisfiableError{Restrictions: []int{i}, Reason: fmt.Sprintf("%q can never pass", r.String())}
+		}
+
+		field := alternatives[0].Field
+		single := true
+		for _, alt := range alternatives {
+			if alt.Field != field {
+				single = false
+				break
+			}
+		}
+		if single {
+			ret[field] = append(ret[field], unitConstraint{index: i, restriction: Restriction{Alternatives: alternatives}, alternatives: alternatives})
+		}
+	}
+
+	return ret, nil
+}
+
+func conflict(a, b unitConstraint, reason string) error {
+	return &UnsatisfiableError{
+		Restrictions: []int{a.index, b.index},
+		Reason:       fmt.Sprintf("%q and %q conflict: %s", a.restriction.String(), b.restriction.String(), reason),
+	}
+}
+
+// proveUnsatisfiable looks for conflicts that make restrictions provably unsatisfiable
+func proveUnsatisfiable(restrictions []Restriction) error {
+	units, err := unitConstraints(restrictions)
+	if err != nil {
+		return err
+	}
+
+	fields := make([]string, 0, len(units))
+	for field := range units {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		constraints := units[field]
+
+		var missing, present *unitConstraint
+		for i := range constraints {
+			c := &constraints[i]
+			allMissing, anyMissing := true, false
+			for _, alt := range c.alternatives {
+				if alt.Cond == "!" {
+					anyMissing = true
+				} else {
+					allMissing = false
+				}
+			}
+			if allMissing && missing == nil {
+				missing = c
+			}
+			if !anyMissing && present == nil {
+				present = c
+			}
+		}
+		if missing != nil && present != nil {
+			return conflict(*missing, *present, fmt.Sprintf("%s must be both missing and present", field))
+		}
+
+		// Equality pins the value exactly, so everything else can be evaluated
+		for _, pin := range constraints {
+			if len(pin.alternatives) != 1 || pin.alternatives[0].Cond != "=" {
+				continue
+			}
+			value := fmt.Sprintf("%v", pin.alternatives[0].Value)
+			for _, other := range constraints {
+				ok, _ := other.restriction.Evaluate(map[string]any{field: value})
+				if !ok {
+					return conflict(pin, other, fmt.Sprintf("%s must be %s", field, value))
+				}
+			}
+		}
+
+		err = proveBoundsConflict(field, constraints)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isGreater reports whether a > b
+func isGreater(a, b number) bool {
+	cmp, ok := compareNumbers(a, b)
+	return ok && cmp > 0
+}
+
+func proveBoundsConflict(field string, constraints []unitConstraint) error {
+	var (
+		lower, upper             *unitConstraint
+		lowerNum, upperNum       number
+		lexoLower, lexoUpper     *unitConstraint
+		lexoLowerStr, lexoUpperS string
+	)
+
+	for i := range constraints {
+		c := &constraints[i]
+		if len(c.alternatives) != 1 {
+			continue
+		}
+		alt := c.alternatives[0]
+		value := fmt.Sprintf("%v", alt.Value)
+
+		switch alt.Cond {
+		case "<", ">":
+			num, ok := parseNumber(value)
+			if !ok || (num.kind == numberFloat && math.IsNaN(num.f)) {
+				continue
+			}
+			if alt.Cond == ">" && (lower == nil || isGreater(num, lowerNum)) {
+				lower, lowerNum = c, num
+			}
+			if alt.Cond == "<" && (upper == nil || isGreater(upperNum, num)) {
+				upper, upperNum = c, num
+			}
+		case "}":
+			if lexoLower == nil || value > lexoLowerStr {
+				lexoLower, lexoLowerStr = c, value
+			}
+		case "{":
+			if lexoUpper == nil || value < lexoUpperS {
+				lexoUpper, lexoUpperS = c, value
+			}
+		}
+	}
+
+	if lower != nil && upper != nil && !isGreater(upperNum, lowerNum) {
+		return conflict(*lower, *upper, fmt.Sprintf("no number is greater than %v and less than %v", lowerNum, upperNum))
+	}
+
+	// Immediate successor of string s is s + "\x00"
+	if lexoLower != nil && lexoUpper != nil && (lexoLowerStr >= lexoUpperS || lexoUpperS == lexoLowerStr+"\x00") {
+		return conflict(*lexoLower, *lexoUpper, fmt.Sprintf("no string sorts after %q and before %q", lexoLowerStr, lexoUpperS))
+	}
+
+	return nil
+}
+
+// candidates returns interesting values for field (nil means missing)
+func candidates(field string, restrictions []Restriction) []*string {
+	var prefixes, suffixes, contains []string
+	seen := make(map[string]bool)
+	ret := []*string{nil}
+
+	add := func(s string) {
+		if !seen[s] {
+			seen[s] = true
+			value := s
+			ret = append(ret, &value)
+		}
+	}
+
+	add("")
+	add("x")
+
+	for _, r := range restrictions {
+		for _, alt := range r.Alternatives {
+			if alt.Field != field {
+				continue
+			}
+			value := fmt.Sprintf("%v", alt.Value)
+			add(value)
+			add(value + "x")
+			add(value + "\x00")
+			if len(value) > 0 {
+				add(value[:len(value)-1])
+			}
+
+			if num, err := strconv.ParseInt(value, 10, 64); err == nil {
+				add(strconv.FormatInt(num-1, 10))
+				add(strconv.FormatInt(num+1, 10))
+			}
+			if num, err := strconv.ParseFloat(value, 64); err == nil {
+				add(strconv.FormatFloat(num-0.5, 'f', -1, 64))
+				add(strconv.FormatFloat(num+0.5, 'f', -1, 64))
+			}
+
+			switch alt.Cond {
+			case "^":
+				prefixes = append(prefixes, value)
+			case "$":
+				suffixes = append(suffixes, value)
+			case "~":
+				contains = append(contains, value)
+			}
+		}
+	}
+
+	prefixes = append(prefixes, "")
+	suffixes = append(suffixes, "")
+	contains = append(contains, "")
+	for _, p := range prefixes {
+		for _, c := range contains {
+			for _, s := range suffixes {
+				add(p + c + s)
+			}
+		}
+	}
+
+	return ret
+}
+
+// findWitness searches for values satisfying all restrictions and (when given) failing fail
+func findWitness(restrictions []Restriction, fail *Restriction) (map[string]any, bool) {
+	type check struct {
+		restriction Restriction
+		expected    bool
+	}
+
+	all := restrictions
+	if fail != nil {
+		all = append(append([]Restriction{}, restrictions...), *fail)
+	}
+
+	fieldSet := make(map[string]bool)
+	for _, r := range all {
+		for _, alt := range r.Alternatives {
+			fieldSet[alt.Field] = true
+		}
+	}
+
+	fields := make([]string, 0, len(fieldSet))
+	for field := range fieldSet {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	order := make(map[string]int)
+	for i, field := range fields {
+		order[field] = i
+	}
+
+	// checks[i] can be evaluated once first i fields are assigned
+	checks := make([][]check, len(fields)+1)
+	for i, r := range all {
+		last := -1
+		for _, alt := range r.Alternatives {
+			if order[alt.Field] > last {
+				last = order[alt.Field]
+			}
+		}
+		checks[last+1] = append(checks[last+1], check{restriction: r, expected: fail == nil || i < len(restrictions)})
+	}
+
+	vals := make(map[string]any)
+	pass := func(level int) bool {
+		for _, c := range checks[level] {
+			ok, _ := c.restriction.Evaluate(vals)
+			if ok != c.expected {
+				return false
+			}
+		}
+		return true
+	}
+
+	values := make([][]*string, len(fields))
+	for i, field := range fields {
+		values[i] = candidates(field, all)
+	}
+
+	steps := 0
+	var search func(level int) bool
+	search = func(level int) bool {
+		if level == len(fields) {
+			return true
+		}
+		field := fields[level]
+		for _, value := range values[level] {
+			steps++
+			if steps > maxSatisfiableSteps {
+				return false
+			}
+
+			if value == nil {
+				delete(vals, field)
+			} else {
+				vals[field] = *value
+			}
+
+			if pass(level+1) && search(level+1) {
+				return true
+			}
+		}
+		delete(vals, field)
+		return false
+	}
+
+	if !search(0) {
+		return nil, false
+	}
+
+	return vals, true
+}
@@ -0,0 +1,91 @@
+package runes
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSatisfiable(t *testing.T) {
+	for _, str := range []string{
+		"",
+		"method=listpeers",
+		"method^list&method$peers&method~stp",
+		"time<100&time>98",
+		"time<100&time>99",
+		"time<100|method=pay&time>200|method/pay&method!|time=300",
+		"method{b&method}a",
+		"method/pay&method^pay",
+		"pnum!|pnum>1&pnum<3",
+		"foo#bar&method!",
+	} {
+		restrictions, err := MakeRestrictionsFromString(str)
+		assert.NoError(t, err, str)
+
+		witness, err := satisfiable(restrictions)
+		if assert.NoError(t, err, str) {
+			assert.True(t, evaluateRestrictions(restrictions, witness), str)
+		}
+	}
+}
+
+func TestSatisfiableUniqueID(t *testing.T) {
+	master := MustMakeMasterRune([]byte("secret"))
+	id, err := UniqueID("1", "2")
+	assert.NoError(t, err)
+	r, err := master.GetRestricted(*id, MustMakeRestrictionsFromString("method=pay")[0])
+	assert.NoError(t, err)
+
+	witness, err := r.Satisfiable()
+	assert.NoError(t, err)
+	ok, _ := r.Evaluate(witness)
+	assert.True(t, ok)
+}
+
+func TestUnsatisfiable(t *testing.T) {
+	for _, c := range []struct {
+		str          string
+		restrictions []int
+	}{
+		{"method=a&method=b", []int{0, 1}},
+		{"method=pay&method!", []int{1, 0}},
+		{"method^list&method!", []int{1, 0}},
+		{"method=listpeers&method^pay", []int{0, 1}},
+		{"time<100&time>100", []int{1, 0}},
+		{"time<100&time>99.5&time<99", []int{1, 2}},
+		{"time<abc", []int{0}},
+		{"time<abc|time>NaN", []int{0}},
+		{"time<-Inf|time>+Inf", []int{0}},
+		{"time>18446744073709551616&time<18446744073709551615", []int{0, 1}},
+		{"method{a&method}b", []int{1, 0}},
+		{"method}a&method{a\x00", []int{0, 1}},
+		{"id=1&method=a&method=b|method=c", []int{1, 2}},
+	} {
+		restrictions, err := MakeRestrictionsFromString(c.str)
+		assert.NoError(t, err, c.str)
+
+		_, err = satisfiable(restrictions)
+		assert.ErrorIs(t, err, ErrUnsatisfiable, c.str)
+
+		var unsat *UnsatisfiableError
+		if assert.True(t, errors.As(err, &unsat), c.str) {
+			assert.Equal(t, c.restrictions, unsat.Restrictions, c.str)
+			assert.NotEmpty(t, unsat.Reason)
+		}
+	}
+}
+
+func TestIssueRequireSatisfiable(t *testing.T) {
+	master := MustMakeMasterRune([]byte("secret"))
+
+	r, err := master.Issue(IssueOptions{RequireSatisfiable: true}, MustMakeRestrictionsFromString("method=pay")...)
+	assert.NoError(t, err)
+	assert.True(t, master.IsRuneAuthorized(r))
+
+	_, err = master.Issue(IssueOptions{RequireSatisfiable: true}, MustMakeRestrictionsFromString("time<10&time>20")...)
+	assert.ErrorIs(t, err, ErrUnsatisfiable)
+
+	_, err = master.Issue(IssueOptions{}, MustMakeRestrictionsFromString("time<10&time>20")...)
+	assert.NoError(t, err)
+}
@@ -1,13 +1,18 @@
 package runes
 
 import (
-	"crypto/sha256"
 	"encoding/binary"
-	"hash"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/bits"
 	"os"
 )
 
-// The purpose of all this is to extract (and be able to set) SHA-256 midstates
+// The purpose of all this is to extract (and be able to set) SHA-256 midstates.
+// We carry our own implementation of the compression function so we do not depend
+// on the private marshalling format of crypto/sha256.
 
 const (
 	// ChunkSize is chunk size in bytes
@@ -15,21 +20,64 @@ const (
 	// OutputSize is size of SHA256 checksum in bytes.
 	OutputSize = 32
 	// Magic256 is the magic for SHA256
+	//
+	// Deprecated: it was the magic of crypto/sha256 marshalled state which is no longer used.
 	Magic256 = "sha\x03"
 )
 
-// Sha256 struct
-type Sha256 struct {
-	hasher hash.Hash
-	len    uint64
-}
-
 // Marshaller is the interface for marshalling
+//
+// Deprecated: use encoding.BinaryMarshaler and encoding.BinaryUnmarshaler (implemented by Sha256 and MidState).
 type Marshaller interface {
 	UnmarshalBinary([]byte) error
 	MarshalBinary() ([]byte, error)
 }
 
+var (
+	// ErrUnalignedMidState represents an error where midstate length is not a multiple of ChunkSize
+	ErrUnalignedMidState = errors.New("midstate length is not aligned to chunk size")
+	// ErrInvalidMidState represents an error where serialized state could not be loaded
+	ErrInvalidMidState = errors.New("invalid serialized state")
+)
+
+const (
+	midStateMagic = "rms\x01"
+	sha256Magic   = "rsh\x01"
+
+	midStateMarshaledSize = len(midStateMagic) + 8*4 + 8
+	sha256MarshaledSize   = len(sha256Magic) + 8*4 + 8 + 1 + ChunkSize
+)
+
+var initialH = [8]uint32{
+	0x6a09e667,
+	0xbb67ae85,
+	0x3c6ef372,
+	0xa54ff53a,
+	0x510e527f,
+	0x9b05688c,
+	0x1f83d9ab,
+	0x5be0cd19,
+}
+
+var roundK = [64]uint32{
+	0x428a2f98, 0x71374491, 0xb5c0fbcf, 0xe9b5dba5, 0x3956c25b, 0x59f111f1, 0x923f82a4, 0xab1c5ed5,
+	0xd807aa98, 0x12835b01, 0x243185be, 0x550c7dc3, 0x72be5d74, 0x80deb1fe, 0x9bdc06a7, 0xc19bf174,
+	0xe49b69c1, 0xefbe4786, 0x0fc19dc6, 0x240ca1cc, 0x2de92c6f, 0x4a7484aa, 0x5cb0a9dc, 0x76f988da,
+	0x983e5152, 0xa831c66d, 0xb00327c8, 0xbf597fc7, 0xc6e00bf3, 0xd5a79147, 0x06ca6351, 0x14292967,
+	0x27b70a85, 0x2e1b2138, 0x4d2c6dfc, 0x53380d13, 0x650a7354, 0x766a0abb, 0x81c2c92e, 0x92722c85,
+	0xa2bfe8a1, 0xa81a664b, 0xc24b8b70, 0xc76c51a3, 0xd192e819, 0xd6990624, 0xf40e3585, 0x106aa070,
+	0x19a4c116, 0x1e376c08, 0x2748774c, 0x34b0bcb5, 0x391c0cb3, 0x4ed8aa4a, 0x5b9cca4f, 0x682e6ff3,
+	0x748f82ee, 0x78a5636f, 0x84c87814, 0x8cc70208, 0x90befffa, 0xa4506ceb, 0xbef9a3f7, 0xc67178f2,
+}
+
+// Sha256 struct
+type Sha256 struct {
+	h   [8]uint32
+	buf [ChunkSize]byte
+	nx  int
+	len uint64
+}
+
 // MidState struct
 type MidState struct {
 	H   [8]uint32
@@ -49,43 +97,55 @@ func (state *MidState) GetSum() [OutputSize]byte {
 
 // NewSha256 - construct new instance
 func NewSha256() *Sha256 {
-	return &Sha256{hasher: sha256.New(), len: uint64(0)}
+	ret := &Sha256{}
+	ret.Reset()
+	return ret
 }
 
 // Write - add bytes
 func (s *Sha256) Write(p []byte) (nn int, err error) {
-	s.len += uint64(len(p))
-	return s.hasher.Write(p)
+	nn = len(p)
+	s.len += uint64(nn)
+
+	if s.nx > 0 {
+		n := copy(s.buf[s.nx:], p)
+		s.nx += n
+		if s.nx == ChunkSize {
+			block(&s.h, s.buf[:])
+			s.nx = 0
+		}
+		p = p[n:]
+	}
+
+	if len(p) >= ChunkSize {
+		n := len(p) &^ (ChunkSize - 1)
+		block(&s.h, p[:n])
+		p = p[n:]
+	}
+
+	if len(p) > 0 {
+		s.nx = copy(s.buf[:], p)
+	}
+
+	return nn, nil
 }
 
 // Reset - reset instance
 func (s *Sha256) Reset() {
+	s.h = initialH
+	s.nx = 0
 	s.len = uint64(0)
-	s.hasher.Reset()
+}
+
+// Clone returns an independent copy of the hasher
+func (s *Sha256) Clone() *Sha256 {
+	ret := *s
+	return &ret
 }
 
 // GetMidState - get the internal state
 func (s *Sha256) GetMidState() *MidState {
-	m := s.hasher.(Marshaller)
-	if m == nil {
-		return nil
-	}
-	b, err := m.MarshalBinary()
-	if err != nil {
-		return nil
-	}
-
-	ret := &MidState{}
-
-	b = b[len(Magic256):]
-	for i := 0; i < 8; i++ {
-		b, ret.H[i] = consumeUint32(b)
-	}
-
-	b = b[len(b)-8:]
-	_, ret.Len = consumeUint64(b)
-
-	return ret
+	return &MidState{H: s.h, Len: s.len}
 }
 
 // SetMidState - updates internal state
@@ -94,31 +154,14 @@ func (s *Sha256) SetMidState(state *MidState) error {
 		return os.ErrInvalid
 	}
 
-	m := s.hasher.(Marshaller)
-	if m == nil {
-		return nil
-	}
-
-	s.Reset()
-
-	marshaledSize := len(Magic256) + 8*4 + ChunkSize + 8
-	b := make([]byte, 0, marshaledSize)
-	b = append(b, Magic256...)
-
-	for i := 0; i < 8; i++ {
-		b = appendUint32(b, state.H[i])
+	// Midstate carries no buffered bytes so it must end on a chunk boundary
+	if state.Len%ChunkSize != 0 {
+		return ErrUnalignedMidState
 	}
 
-	// Critical assumption here is there are no leftover bytes
-	b = appendUint64(b, state.Len)
-
-	// Extend
-	b = b[:marshaledSize]
-
-	err := m.UnmarshalBinary(b)
-	if err != nil {
-		return err
-	}
+	s.h = state.H
+	s.nx = 0
+	s.len = state.Len
 
 	return nil
 }
@@ -152,15 +195,12 @@ func (s *Sha256) AddPadding() error {
 	padlen := tmp[:t+8]
 
 	binary.BigEndian.PutUint64(padlen[t+0:], l)
-	//fmt.Printf("T is %d, len is %d, l is %d,  padlen %s len %d\n", t, s.len, l, hex.EncodeToString(padlen), len(padlen))
 
-	_, err := s.hasher.Write(padlen)
+	_, err := s.Write(padlen)
 	if err != nil {
 		return err
 	}
 
-	s.len += uint64(len(padlen))
-
 	return nil
 }
 
@@ -170,6 +210,264 @@ func (s *Sha256) GetSum() [OutputSize]byte {
 	return ret
 }
 
+// MarshalBinary encodes the midstate
+func (state *MidState) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, midStateMarshaledSize)
+	b = append(b, midStateMagic...)
+	for i := 0; i < 8; i++ {
+		b = appendUint32(b, state.H[i])
+	}
+	b = appendUint64(b, state.Len)
+
+	return b, nil
+}
+
+// UnmarshalBinary decodes the midstate
+func (state *MidState) UnmarshalBinary(b []byte) error {
+	if len(b) != midStateMarshaledSize {
+		return fmt.Errorf("%w: midstate must be %d bytes, got %d", ErrInvalidMidState, midStateMarshaledSize, len(b))
+	}
+	if string(b[:len(midStateMagic)]) != midStateMagic {
+		return fmt.Errorf("%w: bad midstate magic", ErrInvalidMidState)
+	}
+
+	b = b[len(midStateMagic):]
+	for i := 0; i < 8; i++ {
+		b, state.H[i] = consumeUint32(b)
+	}
+	_, length := consumeUint64(b)
+	if err := checkMidStateLen(length); err != nil {
+		return err
+	}
+	state.Len = length
+
+	return nil
+}
+
+// checkMidStateLen checks that midstate covers whole chunks
+func checkMidStateLen(length uint64) error {
+	if length%ChunkSize != 0 {
+		return fmt.Errorf("%w: length %d is not a multiple of %d", ErrInvalidMidState, length, ChunkSize)
+	}
+	return nil
+}
+
+// checkBuffered checks that buffered byte count matches the total length
+func checkBuffered(length uint64, nx int) error {
+	if length%ChunkSize != uint64(nx) {
+		return fmt.Errorf("%w: %d bytes buffered but length is %d", ErrInvalidMidState, nx, length)
+	}
+	return nil
+}
+
+type midStateJSON struct {
+	H   string `json:"h"`
+	Len uint64 `json:"len"`
+}
+
+type sha256JSON struct {
+	H      string `json:"h"`
+	Len    uint64 `json:"len"`
+	Buffer string `json:"buffer"`
+}
+
+func encodeH(h *[8]uint32) string {
+	b := make([]byte, 0, 8*4)
+	for i := 0; i < 8; i++ {
+		b = appendUint32(b, h[i])
+	}
+
+	return hex.EncodeToString(b)
+}
+
+func decodeH(str string, h *[8]uint32) error {
+	b, err := hex.DecodeString(str)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMidState, err)
+	}
+	if len(b) != 8*4 {
+		return fmt.Errorf("%w: state must be %d bytes, got %d", ErrInvalidMidState, 8*4, len(b))
+	}
+	for i := 0; i < 8; i++ {
+		b, h[i] = consumeUint32(b)
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the midstate as JSON
+func (state *MidState) MarshalJSON() ([]byte, error) {
+	return json.Marshal(midStateJSON{H: encodeH(&state.H), Len: state.Len})
+}
+
+// UnmarshalJSON decodes the midstate from JSON
+func (state *MidState) UnmarshalJSON(data []byte) error {
+	var tmp midStateJSON
+	err := json.Unmarshal(data, &tmp)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMidState, err)
+	}
+
+	var h [8]uint32
+	err = decodeH(tmp.H, &h)
+	if err != nil {
+		return err
+	}
+	err = checkMidStateLen(tmp.Len)
+	if err != nil {
+		return err
+	}
+
+	state.H = h
+	state.Len = tmp.Len
+
+	return nil
+}
+
+// MarshalBinary encodes the complete hasher state (including buffered bytes)
+func (s *Sha256) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, sha256MarshaledSize)
+	b = append(b, sha256Magic...)
+	for i := 0; i < 8; i++ {
+		b = appendUint32(b, s.h[i])
+	}
+	b = appendUint64(b, s.len)
+	b = append(b, byte(s.nx))
+	b = append(b, s.buf[:s.nx]...)
+
+	// Rest of the buffer is zero
+	b = b[:sha256MarshaledSize]
+
+	return b, nil
+}
+
+// UnmarshalBinary decodes the complete hasher state
+func (s *Sha256) UnmarshalBinary(b []byte) error {
+	if len(b) != sha256MarshaledSize {
+		return fmt.Errorf("%w: state must be %d bytes, got %d", ErrInvalidMidState, sha256MarshaledSize, len(b))
+	}
+	if string(b[:len(sha256Magic)]) != sha256Magic {
+		return fmt.Errorf("%w: bad state magic", ErrInvalidMidState)
+	}
+
+	var ret Sha256
+
+	b = b[len(sha256Magic):]
+	for i := 0; i < 8; i++ {
+		b, ret.h[i] = consumeUint32(b)
+	}
+	b, ret.len = consumeUint64(b)
+
+	ret.nx = int(b[0])
+	if ret.nx >= ChunkSize {
+		return fmt.Errorf("%w: buffered length %d too big", ErrInvalidMidState, ret.nx)
+	}
+	copy(ret.buf[:], b[1:1+ret.nx])
+	for _, c := range b[1+ret.nx:] {
+		if c != 0 {
+			return fmt.Errorf("%w: non-zero padding after buffered bytes", ErrInvalidMidState)
+		}
+	}
+
+	err := checkBuffered(ret.len, ret.nx)
+	if err != nil {
+		return err
+	}
+
+	*s = ret
+
+	return nil
+}
+
+// MarshalJSON encodes the complete hasher state as JSON
+func (s *Sha256) MarshalJSON() ([]byte, error) {
+	return json.Marshal(sha256JSON{H: encodeH(&s.h), Len: s.len, Buffer: hex.EncodeToString(s.buf[:s.nx])})
+}
+
+// UnmarshalJSON decodes the complete hasher state from JSON
+func (s *Sha256) UnmarshalJSON(data []byte) error {
+	var tmp sha256JSON
+	err := json.Unmarshal(data, &tmp)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMidState, err)
+	}
+
+	var ret Sha256
+
+	err = decodeH(tmp.H, &ret.h)
+	if err != nil {
+		return err
+	}
+	ret.len = tmp.Len
+
+	buf, err := hex.DecodeString(tmp.Buffer)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMidState, err)
+	}
+	if len(buf) >= ChunkSize {
+		return fmt.Errorf("%w: buffered length %d too big", ErrInvalidMidState, len(buf))
+	}
+	ret.nx = copy(ret.buf[:], buf)
+
+	err = checkBuffered(ret.len, ret.nx)
+	if err != nil {
+		return err
+	}
+
+	*s = ret
+
+	return nil
+}
+
+// block runs the SHA-256 compression function over p (which must be a multiple of ChunkSize)
+func block(h *[8]uint32, p []byte) {
+	var w [64]uint32
+
+	h0, h1, h2, h3, h4, h5, h6, h7 := h[0], h[1], h[2], h[3], h[4], h[5], h[6], h[7]
+
+	for len(p) >= ChunkSize {
+		for i := 0; i < 16; i++ {
+			w[i] = binary.BigEndian.Uint32(p[i*4:])
+		}
+		for i := 16; i < 64; i++ {
+			v1 := w[i-2]
+			t1 := bits.RotateLeft32(v1, -17) ^ bits.RotateLeft32(v1, -19) ^ (v1 >> 10)
+			v2 := w[i-15]
+			t2 := bits.RotateLeft32(v2, -7) ^ bits.RotateLeft32(v2, -18) ^ (v2 >> 3)
+			w[i] = t1 + w[i-7] + t2 + w[i-16]
+		}
+
+		a, b, c, d, e, f, g, hh := h0, h1, h2, h3, h4, h5, h6, h7
+
+		for i := 0; i < 64; i++ {
+			t1 := hh + (bits.RotateLeft32(e, -6) ^ bits.RotateLeft32(e, -11) ^ bits.RotateLeft32(e, -25)) + ((e & f) ^ (^e & g)) + roundK[i] + w[i]
+			t2 := (bits.RotateLeft32(a, -2) ^ bits.RotateLeft32(a, -13) ^ bits.RotateLeft32(a, -22)) + ((a & b) ^ (a & c) ^ (b & c))
+
+			hh = g
+			g = f
+			f = e
+			e = d + t1
+			d = c
+			c = b
+			b = a
+			a = t1 + t2
+		}
+
+		h0 += a
+		h1 += b
+		h2 += c
+		h3 += d
+		h4 += e
+		h5 += f
+		h6 += g
+		h7 += hh
+
+		p = p[ChunkSize:]
+	}
+
+	h[0], h[1], h[2], h[3], h[4], h[5], h[6], h[7] = h0, h1, h2, h3, h4, h5, h6, h7
+}
+
 func appendUint64(b []byte, x uint64) []byte {
 	var a [8]byte
 	binary.BigEndian.PutUint64(a[:], x)
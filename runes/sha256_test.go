@@ -1,9 +1,12 @@
 package runes
 
 import (
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -91,3 +94,167 @@ func TestEmpty(t *testing.T) {
 	result = base64.URLEncoding.EncodeToString(midsum[:])
 	assert.Equal(t, CORRECT, result)
 }
+
+func TestAgainstStdlib(t *testing.T) {
+	data := make([]byte, 4*ChunkSize+1)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	for l := 0; l <= len(data); l++ {
+		x := NewSha256()
+		x.Write(data[:l])
+		x.AddPadding()
+
+		assert.Equal(t, sha256.Sum256(data[:l]), x.GetSum(), "length %d", l)
+	}
+}
+
+func TestSplitWrites(t *testing.T) {
+	data := make([]byte, 3*ChunkSize+5)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	for split := 0; split <= len(data); split++ {
+		x := NewSha256()
+		x.Write(data[:split])
+		x.Write(data[split:])
+		x.AddPadding()
+
+		assert.Equal(t, sha256.Sum256(data), x.GetSum(), "split %d", split)
+	}
+}
+
+func TestClone(t *testing.T) {
+	x := NewSha256()
+	x.Write([]byte("burek"))
+
+	y := x.Clone()
+	y.Write([]byte("mesni"))
+	y.AddPadding()
+
+	x.AddPadding()
+	assert.Equal(t, sha256.Sum256([]byte("burek")), x.GetSum())
+	assert.Equal(t, sha256.Sum256([]byte("burekmesni")), y.GetSum())
+}
+
+func TestUnalignedMidState(t *testing.T) {
+	x := NewSha256()
+
+	err := x.SetMidState(&MidState{Len: 3})
+	assert.ErrorIs(t, err, ErrUnalignedMidState)
+
+	err = x.SetMidState(&MidState{Len: ChunkSize})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(ChunkSize), x.GetLen())
+}
+
+func TestMidStateBinary(t *testing.T) {
+	x := NewSha256()
+	x.Write([]byte("burek"))
+	x.AddPadding()
+
+	b, err := x.GetMidState().MarshalBinary()
+	assert.NoError(t, err)
+
+	var state MidState
+	err = state.UnmarshalBinary(b)
+	assert.NoError(t, err)
+	assert.Equal(t, *x.GetMidState(), state)
+
+	err = state.UnmarshalBinary(b[:len(b)-1])
+	assert.ErrorIs(t, err, ErrInvalidMidState)
+
+	b[0] ^= 0xff
+	err = state.UnmarshalBinary(b)
+	assert.ErrorIs(t, err, ErrInvalidMidState)
+
+	// Length not aligned to chunk size
+	b[0] ^= 0xff
+	b[len(b)-1]++
+	err = state.UnmarshalBinary(b)
+	assert.ErrorIs(t, err, ErrInvalidMidState)
+}
+
+func TestMidStateJSON(t *testing.T) {
+	x := NewSha256()
+	x.Write([]byte("burek"))
+	x.AddPadding()
+
+	b, err := json.Marshal(x.GetMidState())
+	assert.NoError(t, err)
+
+	var state MidState
+	err = json.Unmarshal(b, &state)
+	assert.NoError(t, err)
+	assert.Equal(t, *x.GetMidState(), state)
+
+	for _, bad := range []string{`{"h":"00","len":64}`, `{"h":"zz","len":64}`, `{"h":"` + strings.Repeat("00", 32) + `","len":65}`, `[]`, `{"h":1}`} {
+		err = json.Unmarshal([]byte(bad), &state)
+		assert.ErrorIs(t, err, ErrInvalidMidState, bad)
+	}
+}
+
+func TestSha256StateRoundTrip(t *testing.T) {
+	x := NewSha256()
+	x.Write([]byte("burek"))
+	x.AddPadding()
+	// Leave some bytes buffered
+	x.Write([]byte("mesni"))
+
+	b, err := x.MarshalBinary()
+	assert.NoError(t, err)
+	y := NewSha256()
+	err = y.UnmarshalBinary(b)
+	assert.NoError(t, err)
+
+	j, err := json.Marshal(x)
+	assert.NoError(t, err)
+	z := NewSha256()
+	err = json.Unmarshal(j, z)
+	assert.NoError(t, err)
+
+	for _, h := range []*Sha256{x, y, z} {
+		h.AddPadding()
+	}
+	assert.Equal(t, x.GetSum(), y.GetSum())
+	assert.Equal(t, x.GetSum(), z.GetSum())
+
+	err = y.UnmarshalBinary(b[1:])
+	assert.ErrorIs(t, err, ErrInvalidMidState)
+
+	b[len(sha256Magic)+8*4+8] = ChunkSize
+	err = y.UnmarshalBinary(b)
+	assert.ErrorIs(t, err, ErrInvalidMidState)
+
+	err = json.Unmarshal([]byte(`{"h":"`+strings.Repeat("00", 32)+`","len":0,"buffer":"`+strings.Repeat("00", ChunkSize)+`"}`), z)
+	assert.ErrorIs(t, err, ErrInvalidMidState)
+
+	// Unused part of the buffer must be zero
+	b, err = x.MarshalBinary()
+	assert.NoError(t, err)
+	b[len(b)-1] = 1
+	err = y.UnmarshalBinary(b)
+	assert.ErrorIs(t, err, ErrInvalidMidState)
+	b[len(b)-1] = 0
+	assert.NoError(t, y.UnmarshalBinary(b))
+
+	// Length does not match buffered byte count
+	b[len(sha256Magic)+8*4+8] = 3
+	b[len(sha256Magic)+8*4+7]++
+	err = y.UnmarshalBinary(b)
+	assert.ErrorIs(t, err, ErrInvalidMidState)
+
+	err = json.Unmarshal([]byte(`{"h":"`+strings.Repeat("00", 32)+`","len":0,"buffer":"000000"}`), z)
+	assert.ErrorIs(t, err, ErrInvalidMidState)
+}
+
+func TestMarshaller(t *testing.T) {
+	for _, m := range []Marshaller{NewSha256(), &MidState{}} {
+		data, err := m.MarshalBinary()
+		assert.NoError(t, err)
+		assert.NoError(t, m.UnmarshalBinary(data))
+	}
+	assert.Equal(t, "sha\x03", Magic256)
+}
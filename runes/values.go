@@ -0,0 +1,113 @@
+package runes
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// ErrNotStruct represents an error where StructValues is given something else than a struct
+var ErrNotStruct = errors.New("values must be a struct or pointer to struct")
+
+// Values provides field values for evaluation. A value can be ObtainValue or ObtainValueCtx, evaluation calls it
+// at most once.
+type Values interface {
+	// Lookup returns the value of field and whether it is present
+	Lookup(field string) (any, bool)
+}
+
+// MapValues adapts a map to Values
+type MapValues map[string]any
+
+// Lookup returns the value of field
+func (m MapValues) Lookup(field string) (any, bool) {
+	val, ok := m[field]
+	return val, ok
+}
+
+// ValuesFunc adapts a function to Values
+type ValuesFunc func(field string) (any, bool)
+
+// Lookup returns the value of field
+func (f ValuesFunc) Lookup(field string) (any, bool) {
+	return f(field)
+}
+
+type structValues struct {
+	value  reflect.Value
+	fields map[string]int
+}
+
+// StructValues adapts a struct (or pointer to struct) to Values. Exported fields are available under the name
+// given by the `rune` tag or else under their lowercased name, `rune:"-"` hides a field. Nil pointers are
+// treated as missing fields.
+func StructValues(v any) (Values, error) {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil, ErrNotStruct
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, ErrNotStruct
+	}
+
+	ret := &structValues{value: value, fields: make(map[string]int)}
+	typ := value.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := strings.ToLower(field.Name)
+		if tag, ok := field.Tag.Lookup("rune"); ok {
+			if tag == "-" {
+				continue
+			}
+			name = tag
+		}
+		ret.fields[name] = i
+	}
+
+	return ret, nil
+}
+
+// Lookup returns the value of field
+func (s *structValues) Lookup(field string) (any, bool) {
+	i, ok := s.fields[field]
+	if !ok {
+		return nil, false
+	}
+
+	value := s.value.Field(i)
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil, false
+		}
+		value = value.Elem()
+	}
+	if value.Kind() == reflect.Func && value.IsNil() {
+		return nil, false
+	}
+
+	return value.Interface(), true
+}
+
+// EvaluateValues evaluates the rune, only fields referenced by restrictions are looked up and each of them once
+func (r *Rune) EvaluateValues(vals Values) (bool, string) {
+	result := r.EvaluateValuesResult(vals)
+	return result.OK(), result.Reason()
+}
+
+// EvaluateValuesResult evaluates the rune like EvaluateValues and records the details
+func (r *Rune) EvaluateValuesResult(vals Values) *EvalResult {
+	return r.evaluateResult(contextLookup(context.Background(), vals), EvalOptions{})
+}
+
+// CheckValues checks rune against values
+func (r *Rune) CheckValues(vals Values) error {
+	return r.CheckContext(context.Background(), vals, EvalOptions{})
+}
@@ -0,0 +1,115 @@
+package runes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateValues(t *testing.T) {
+	r, err := FromAuthCode(make([]byte, 32), MustMakeRestrictionsFromString("method^list|method^get&time<100&pnum!"))
+	assert.NoError(t, err)
+
+	for _, vals := range []map[string]any{
+		{"method": "listpeers", "time": 50},
+		{"method": "getinfo", "time": "99"},
+		{"method": "pay", "time": 50},
+		{"method": "listpeers", "time": 150},
+		{"method": "listpeers", "time": 50, "pnum": 1},
+		{},
+	} {
+		expectedOK, expectedMsg := r.Evaluate(vals)
+		ok, msg := r.EvaluateValues(MapValues(vals))
+		assert.Equal(t, expectedOK, ok, vals)
+		assert.Equal(t, expectedMsg, msg, vals)
+	}
+}
+
+func TestEvaluateValuesResolvesOnce(t *testing.T) {
+	r, err := FromAuthCode(make([]byte, 32), MustMakeRestrictionsFromString("method^list|method^get|method=summary&method/listdatastore&time<100"))
+	assert.NoError(t, err)
+
+	lookups := make(map[string]int)
+	vals := ValuesFunc(func(field string) (any, bool) {
+		lookups[field]++
+		switch field {
+		case "method":
+			return "summary", true
+		case "time":
+			return 50, true
+		case "unused":
+			t.Fatal("unused field looked up")
+		}
+		return nil, false
+	})
+
+	assert.NoError(t, r.CheckValues(vals))
+	assert.Equal(t, map[string]int{"method": 1, "time": 1}, lookups)
+
+	// ObtainValue in a map is also called only once, plain maps are no different
+	calls := 0
+	lazy := map[string]any{"method": ObtainValue(func() any { calls++; return "summary" }), "time": 1}
+	ok, _ := r.EvaluateValues(MapValues(lazy))
+	assert.True(t, ok)
+	assert.Equal(t, 1, calls)
+	ok, _ = r.Evaluate(lazy)
+	assert.True(t, ok)
+	assert.Equal(t, 2, calls)
+
+	// Later restrictions are not looked up after a failure
+	lookups = make(map[string]int)
+	err = r.CheckValues(ValuesFunc(func(field string) (any, bool) {
+		lookups[field]++
+		return "pay", true
+	}))
+	assert.EqualError(t, err, "does not start with list AND does not start with get AND != summary")
+	assert.Equal(t, map[string]int{"method": 1}, lookups)
+}
+
+func TestStructValues(t *testing.T) {
+	type request struct {
+		Method  string
+		Time    *int64
+		Peer    string `rune:"pnum"`
+		Secret  string `rune:"-"`
+		Lazy    ObtainValue
+		Missing ObtainValue
+		private string
+	}
+
+	now := int64(50)
+	vals, err := StructValues(&request{
+		Method:  "listpeers",
+		Time:    &now,
+		Peer:    "1",
+		Secret:  "hunter2",
+		Lazy:    func() any { return "lazy" },
+		private: "x",
+	})
+	assert.NoError(t, err)
+
+	for field, expected := range map[string]any{"method": "listpeers", "time": int64(50), "pnum": "1"} {
+		val, ok := vals.Lookup(field)
+		assert.True(t, ok, field)
+		assert.Equal(t, expected, val, field)
+	}
+	_, ok := vals.Lookup("lazy")
+	assert.True(t, ok)
+	for _, field := range []string{"secret", "peer", "missing", "private", "Method"} {
+		_, ok := vals.Lookup(field)
+		assert.False(t, ok, field)
+	}
+
+	r, err := FromAuthCode(make([]byte, 32), MustMakeRestrictionsFromString("method^list&time<100&pnum=1&secret!&lazy=lazy&missing!"))
+	assert.NoError(t, err)
+	assert.NoError(t, r.CheckValues(vals))
+
+	vals, err = StructValues(request{Method: "getinfo"})
+	assert.NoError(t, err)
+	assert.EqualError(t, r.CheckValues(vals), "does not start with list")
+
+	for _, v := range []any{nil, 5, (*request)(nil), map[string]any{}} {
+		_, err = StructValues(v)
+		assert.ErrorIs(t, err, ErrNotStruct)
+	}
+}
@@ -0,0 +1,194 @@
+package runes
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultVerifierCacheSize is the default number of cached restriction prefixes
+const DefaultVerifierCacheSize = 1024
+
+// Verifier verifies runes derived from a master rune and caches SHA-256 midstates
+// of restriction prefixes, so verifying a child of an already seen rune only hashes
+// the restrictions it added. It is safe for concurrent use.
+type Verifier struct {
+	master *MasterRune
+	base   MidState
+
+	mutex    sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	lru      *list.List
+	hits     uint64
+	misses   uint64
+}
+
+// VerifierStats contains cache statistics of a verifier
+type VerifierStats struct {
+	Hits   uint64
+	Misses uint64
+	Size   int
+}
+
+type verifierEntry struct {
+	key   string
+	state MidState
+}
+
+// NewVerifier creates a new verifier for master rune caching at most capacity prefixes
+func NewVerifier(master *MasterRune, capacity int) (*Verifier, error) {
+	if master == nil {
+		return nil, fmt.Errorf("nil master rune")
+	}
+	if capacity < 1 {
+		return nil, fmt.Errorf("capacity must be positive")
+	}
+
+	hasher := NewSha256()
+	hasher.Write([]byte(master.SeedSecret))
+	hasher.AddPadding()
+
+	return &Verifier{
+		master:   master,
+		base:     *hasher.GetMidState(),
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}, nil
+}
+
+// prefixKeys returns a key for every prefix of restrictions (keys[i] covers the first i+1 restrictions)
+func prefixKeys(restrictions []Restriction) []string {
+	var sb strings.Builder
+	ends := make([]int, 0, len(restrictions))
+
+	for _, restriction := range restrictions {
+		// Length prefix makes the key unambiguous regardless of restriction content
+		str := restriction.String()
+		sb.WriteString(strconv.Itoa(len(str)))
+		sb.WriteString(":")
+		sb.WriteString(str)
+		ends = append(ends, sb.Len())
+	}
+
+	full := sb.String()
+	keys := make([]string, 0, len(ends))
+	for _, end := range ends {
+		keys = append(keys, full[:end])
+	}
+
+	return keys
+}
+
+// lookup returns the longest cached prefix and its midstate
+func (v *Verifier) lookup(keys []string) (int, MidState) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	for i := len(keys) - 1; i >= 0; i-- {
+		elem, ok := v.entries[keys[i]]
+		if !ok {
+			continue
+		}
+		v.lru.MoveToFront(elem)
+		v.hits++
+		return i + 1, elem.Value.(*verifierEntry).state
+	}
+
+	v.misses++
+	return 0, v.base
+}
+
+// store caches midstates of prefixes keys[start:]
+func (v *Verifier) store(keys []string, start int, states []MidState) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	for i, state := range states {
+		key := keys[start+i]
+		if elem, ok := v.entries[key]; ok {
+			v.lru.MoveToFront(elem)
+			continue
+		}
+
+		v.entries[key] = v.lru.PushFront(&verifierEntry{key: key, state: state})
+	}
+
+	for v.lru.Len() > v.capacity {
+		oldest := v.lru.Back()
+		v.lru.Remove(oldest)
+		delete(v.entries, oldest.Value.(*verifierEntry).key)
+	}
+}
+
+// VerifyAuthCode checks whether authcode was derived from the master rune and restrictions
+func (v *Verifier) VerifyAuthCode(authcode []byte, restrictions []Restriction) error {
+	if len(authcode) != OutputSize {
+		return errAuthMalformed
+	}
+
+	keys := prefixKeys(restrictions)
+	start, state := v.lookup(keys)
+
+	hasher := NewSha256()
+	err := hasher.SetMidState(&state)
+	if err != nil {
+		return err
+	}
+
+	// Midstates are only cached once the auth code verifies, so forged runes cannot evict real ones
+	states := make([]MidState, 0, len(restrictions)-start)
+	for i := start; i < len(restrictions); i++ {
+		hasher.Write([]byte(restrictions[i].String()))
+		hasher.AddPadding()
+		states = append(states, *hasher.GetMidState())
+	}
+
+	err = v.master.compareAuthCode(hasher.GetSum(), authcode, len(restrictions))
+	if err != nil {
+		return err
+	}
+
+	v.store(keys, start, states)
+	return nil
+}
+
+// IsRuneAuthorized check whether rune is authorized
+func (v *Verifier) IsRuneAuthorized(other *Rune) bool {
+	if other == nil {
+		return false
+	}
+
+	return v.VerifyAuthCode(other.GetAuthCode(), other.Restrictions) == nil
+}
+
+// Check checks rune
+func (v *Verifier) Check(rune *Rune, vals map[string]any) error {
+	return v.CheckContext(context.Background(), rune, MapValues(vals), EvalOptions{})
+}
+
+// CheckContext checks that rune is authorized and then evaluates it like Rune.CheckContext
+func (v *Verifier) CheckContext(ctx context.Context, rune *Rune, vals Values, opts EvalOptions) error {
+	if rune == nil {
+		return errAuthNilRune
+	}
+
+	err := v.VerifyAuthCode(rune.GetAuthCode(), rune.Restrictions)
+	if err != nil {
+		return err
+	}
+
+	return rune.CheckContext(ctx, vals, opts)
+}
+
+// Stats returns cache statistics
+func (v *Verifier) Stats() VerifierStats {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	return VerifierStats{Hits: v.hits, Misses: v.misses, Size: v.lru.Len()}
+}
@@ -0,0 +1,105 @@
+package runes
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func makeTestVerifier(t *testing.T, capacity int) (*MasterRune, *Verifier) {
+	secret := make([]byte, 55)
+	_, err := rand.Read(secret)
+	assert.NoError(t, err)
+
+	master := MustMakeMasterRune(secret)
+	verifier, err := NewVerifier(&master, capacity)
+	assert.NoError(t, err)
+
+	return &master, verifier
+}
+
+func TestVerifierCache(t *testing.T) {
+	master, verifier := makeTestVerifier(t, DefaultVerifierCacheSize)
+
+	parent := master.MustGetRestrictedFromString("method^list|method^get&time<100")
+	assert.Equal(t, true, verifier.IsRuneAuthorized(&parent))
+	assert.Equal(t, VerifierStats{Hits: 0, Misses: 1, Size: 2}, verifier.Stats())
+
+	child := parent.MustGetRestrictedFromString("id=3")
+	assert.Equal(t, true, verifier.IsRuneAuthorized(&child))
+	assert.Equal(t, VerifierStats{Hits: 1, Misses: 1, Size: 3}, verifier.Stats())
+
+	// Tampered child does not verify even though parent prefix is cached
+	fake := MustGetFromString(child.String()[:len(child.String())-1] + "4")
+	assert.Equal(t, false, verifier.IsRuneAuthorized(&fake))
+	assert.Equal(t, master.IsRuneAuthorized(&fake), verifier.IsRuneAuthorized(&fake))
+
+	assert.ErrorIs(t, verifier.Check(&fake, nil), ErrUnauthorizedRune)
+	assert.NoError(t, verifier.Check(&child, map[string]any{"method": "listpeers", "time": 50, "id": 3}))
+	assert.Error(t, verifier.Check(&child, map[string]any{"method": "listpeers", "time": 150, "id": 3}))
+}
+
+func TestVerifierEviction(t *testing.T) {
+	master, verifier := makeTestVerifier(t, 3)
+
+	for i := 0; i < 10; i++ {
+		r := master.MustGetRestrictedFromString(fmt.Sprintf("a=%d&b=%d", i, i))
+		assert.Equal(t, true, verifier.IsRuneAuthorized(&r))
+	}
+
+	assert.Equal(t, 3, verifier.Stats().Size)
+}
+
+func TestVerifierRejectedNotCached(t *testing.T) {
+	master, verifier := makeTestVerifier(t, 4)
+
+	parent := master.MustGetRestrictedFromString("method^list|method^get&time<100")
+	assert.Equal(t, true, verifier.IsRuneAuthorized(&parent))
+	size := verifier.Stats().Size
+
+	// Forged runes with fresh restrictions must neither grow the cache nor evict the parent
+	for i := 0; i < 10; i++ {
+		fake, err := FromAuthCode(make([]byte, 32), MustMakeRestrictionsFromString(fmt.Sprintf("a=%d&b=%d&c=%d", i, i, i)))
+		assert.NoError(t, err)
+		assert.Equal(t, false, verifier.IsRuneAuthorized(fake))
+		assert.Equal(t, size, verifier.Stats().Size)
+	}
+
+	child := parent.MustGetRestrictedFromString("id=3")
+	hits := verifier.Stats().Hits
+	assert.Equal(t, true, verifier.IsRuneAuthorized(&child))
+	assert.Equal(t, hits+1, verifier.Stats().Hits)
+}
+
+func TestVerifierConcurrent(t *testing.T) {
+	master, verifier := makeTestVerifier(t, 16)
+	parent := master.MustGetRestrictedFromString("method^list")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				child := parent.MustGetRestrictedFromString(fmt.Sprintf("id=%d&n=%d", i, j))
+				assert.Equal(t, true, verifier.IsRuneAuthorized(&child))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	stats := verifier.Stats()
+	assert.Equal(t, uint64(8*50), stats.Hits+stats.Misses)
+}
+
+func TestNewVerifierInvalid(t *testing.T) {
+	_, err := NewVerifier(nil, 10)
+	assert.Error(t, err)
+
+	_, verifier := makeTestVerifier(t, 1)
+	_, err = NewVerifier(verifier.master, 0)
+	assert.Error(t, err)
+}